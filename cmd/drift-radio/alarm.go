@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultAlarmRamp is how long an alarm takes to fade up to full volume
+const DefaultAlarmRamp = 60 * time.Second
+
+// alarmPoll caps each wait so a suspended laptop still wakes on time:
+// the remaining duration is recomputed from the wall clock every poll
+const alarmPoll = 30 * time.Second
+
+// nextAlarm returns the next occurrence of the wall-clock time spec
+// ("HH:MM", 24-hour) after now. A time that has already passed today
+// means tomorrow.
+func nextAlarm(now time.Time, spec string) (time.Time, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(spec))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid alarm time %q (use HH:MM, e.g. 07:30)", spec)
+	}
+	at := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
+	if !at.After(now) {
+		at = time.Date(now.Year(), now.Month(), now.Day()+1, t.Hour(), t.Minute(), 0, 0, now.Location())
+	}
+	return at, nil
+}
+
+// waitUntil blocks until the wall clock reaches at. It returns false if
+// ctx is cancelled first.
+func waitUntil(ctx context.Context, at time.Time) bool {
+	for {
+		d := time.Until(at)
+		if d <= 0 {
+			return true
+		}
+		if d > alarmPoll {
+			d = alarmPoll
+		}
+		timer := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+	}
+}
+
+// describeAlarm formats an alarm time with how long until it goes off
+func describeAlarm(at time.Time) string {
+	in := time.Until(at).Round(time.Minute).String()
+	if strings.HasSuffix(in, "m0s") {
+		in = strings.TrimSuffix(in, "0s")
+	}
+	return fmt.Sprintf("%s (in %s)", at.Format("Mon 15:04"), in)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// alertWatch follows which kinds of quality alert are up, so each one is
+// announced when it's raised and when it clears rather than on every stats
+// update. Only the stats loop uses it.
+type alertWatch struct {
+	active map[string]bool // kinds raised at the last update
+}
+
+// update takes the alerts that are up now and returns the ones newly
+// raised, and the kinds that have cleared since the last update
+func (w *alertWatch) update(alerts []string) (raised, cleared []string) {
+	now := make(map[string]bool, len(alerts))
+	for _, a := range alerts {
+		kind := alertKind(a)
+		now[kind] = true
+		if !w.active[kind] {
+			raised = append(raised, a)
+		}
+	}
+	for kind := range w.active {
+		if !now[kind] {
+			cleared = append(cleared, kind)
+		}
+	}
+	slices.Sort(cleared)
+	w.active = now
+	return raised, cleared
+}
+
+// announceAlerts tells the user about quality alerts that were raised or
+// cleared since the last stats update, with a bell or desktop notification
+// for new ones when -alert-bell or -alert-notify is set. The full stats
+// block lists the alerts already and is redrawn over anything printed, so
+// the lines only go out with compact stats or plain output; JSON stats
+// get them on stderr.
+func (p *Player) announceAlerts(alerts []string) {
+	raised, cleared := p.alerts.update(alerts)
+	if len(raised) == 0 && len(cleared) == 0 {
+		return
+	}
+	var lines []string
+	for _, a := range raised {
+		lines = append(lines, "⚠️  "+a)
+	}
+	for _, kind := range cleared {
+		lines = append(lines, "✓ Cleared: "+kind)
+	}
+	switch {
+	case p.statsFormat == statsJSON:
+		for _, line := range lines {
+			fmt.Fprintln(os.Stderr, plainText(line))
+		}
+	case p.statsFormat == statsCompact || plainOutput:
+		for _, line := range lines {
+			uiPrintln(line)
+		}
+	}
+	if len(raised) == 0 {
+		return
+	}
+	if p.alertBell {
+		// Keep stdout to the JSON objects
+		if p.statsFormat == statsJSON {
+			fmt.Fprint(os.Stderr, "\a")
+		} else {
+			fmt.Print("\a")
+		}
+	}
+	if p.alertNotify {
+		kinds := make([]string, len(raised))
+		for i, a := range raised {
+			kinds[i] = alertKind(a)
+		}
+		notify("drift-radio: quality alert", strings.Join(kinds, ", "))
+	}
+}
+
+// notifyCommand returns the command that shows a desktop notification, or
+// nil where there's none
+func notifyCommand(title, body string) []string {
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %q with title %q", body, title)
+		return []string{"osascript", "-e", script}
+	case "windows":
+		return nil
+	}
+	return []string{"notify-send", "--app-name=drift-radio", title, body}
+}
+
+// notify shows a desktop notification in the background. A missing
+// notifier is only logged.
+func notify(title, body string) {
+	args := notifyCommand(title, body)
+	if args == nil {
+		slog.Debug("desktop notifications aren't supported on " + runtime.GOOS)
+		return
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	if err := cmd.Start(); err != nil {
+		slog.Debug("could not show a notification", "err", err)
+		return
+	}
+	go func() { _ = cmd.Wait() }()
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // decode GIF artwork
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hhaidrr/cli-radio-player/pkg/radio"
+)
+
+// Inline image protocols for -art
+const (
+	imageKitty = "kitty" // Kitty graphics protocol: kitty, Ghostty
+	imageITerm = "iterm" // iTerm2 inline images: iTerm2, WezTerm
+)
+
+const (
+	artCols         = 24               // width of the artwork in terminal cells
+	artPixels       = 320              // artwork is scaled down to this width before it's sent
+	maxArtSize      = 8 << 20          // largest artwork file read
+	artFetchTimeout = 15 * time.Second // for downloading the artwork
+	kittyArtID      = 1                // Kitty image ID; each station's upload replaces the last
+)
+
+// detectImageProtocol picks the inline image protocol the terminal
+// understands from its environment, or "" when it has none. Escape codes
+// don't get through tmux or screen, so there's none inside them.
+func detectImageProtocol() string {
+	term := os.Getenv("TERM")
+	if plainOutput || os.Getenv("TMUX") != "" || strings.HasPrefix(term, "screen") {
+		return ""
+	}
+	switch {
+	case term == "xterm-kitty", term == "xterm-ghostty", os.Getenv("KITTY_WINDOW_ID") != "":
+		return imageKitty
+	case os.Getenv("TERM_PROGRAM") == "iTerm.app", os.Getenv("TERM_PROGRAM") == "WezTerm", os.Getenv("LC_TERMINAL") == "iTerm2":
+		return imageITerm
+	}
+	return ""
+}
+
+// stationArt is a station's artwork, encoded for the terminal
+type stationArt struct {
+	upload string // sent once before the first draw; Kitty keeps the image
+	draw   string // shows the image at the cursor
+}
+
+// loadArt fetches the artwork for st in the background, for the stats
+// display to draw once it's ready. It does nothing without -art, or when
+// st's artwork is already loaded.
+func (p *Player) loadArt(st radio.Station) {
+	if p.artProtocol == "" {
+		return
+	}
+	p.mu.Lock()
+	if p.artStation == st.URL {
+		p.mu.Unlock()
+		return
+	}
+	p.artStation = st.URL
+	p.art = nil
+	p.mu.Unlock()
+
+	go func() {
+		art, err := p.fetchArt(st)
+		if err != nil {
+			slog.Debug("no artwork", "station", st.Name, "err", err)
+			return
+		}
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		// The station may have changed while this one's art downloaded
+		if p.artStation == st.URL {
+			p.art = art
+			p.artUploaded = false
+		}
+	}()
+}
+
+// fetchArt reads the station's Artwork, or asks yt-dlp for the thumbnail
+// of a page URL, and encodes it for the terminal
+func (p *Player) fetchArt(st radio.Station) (*stationArt, error) {
+	source := st.Artwork
+	if source == "" {
+		if !radio.NeedsResolution(st.URL) {
+			return nil, errors.New("the station has no artwork")
+		}
+		var err error
+		if source, err = radio.Thumbnail(st.URL, p.ResolveOptions()); err != nil {
+			return nil, err
+		}
+	}
+	data, err := readArt(p.Analyzer().Client(), source)
+	if err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", radio.RedactURL(source), err)
+	}
+	return encodeArt(scaleToWidth(img, artPixels), p.artProtocol)
+}
+
+// readArt downloads an http(s) image, or reads a local file
+func readArt(client *http.Client, source string) ([]byte, error) {
+	lower := strings.ToLower(source)
+	if !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") {
+		f, err := os.Open(strings.TrimPrefix(source, "file://"))
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return io.ReadAll(io.LimitReader(f, maxArtSize))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), artFetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("artwork server answered %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxArtSize))
+}
+
+// encodeArt builds the escape codes that upload and show img, artCols
+// cells wide. Cells are about twice as tall as they're wide, which sets
+// the rows it takes up.
+func encodeArt(img image.Image, protocol string) (*stationArt, error) {
+	b := img.Bounds()
+	rows := max(1, (artCols*b.Dy()+b.Dx())/(2*b.Dx()))
+	var buf bytes.Buffer
+	switch protocol {
+	case imageKitty:
+		// Kitty only takes PNG, in chunks of at most 4096 bytes of base64
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, err
+		}
+		data := base64.StdEncoding.EncodeToString(buf.Bytes())
+		var upload strings.Builder
+		for i := 0; i < len(data); i += 4096 {
+			chunk := data[i:min(i+4096, len(data))]
+			more := 0
+			if i+4096 < len(data) {
+				more = 1
+			}
+			if i == 0 {
+				fmt.Fprintf(&upload, "\033_Ga=t,f=100,i=%d,q=2,m=%d;%s\033\\", kittyArtID, more, chunk)
+			} else {
+				fmt.Fprintf(&upload, "\033_Gm=%d;%s\033\\", more, chunk)
+			}
+		}
+		return &stationArt{
+			upload: upload.String(),
+			draw:   fmt.Sprintf("\033_Ga=p,i=%d,c=%d,r=%d,q=2\033\\", kittyArtID, artCols, rows),
+		}, nil
+	case imageITerm:
+		// iTerm2 keeps nothing, so the image is sent with every draw; JPEG
+		// keeps that small
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+			return nil, err
+		}
+		return &stationArt{
+			draw: fmt.Sprintf("\033]1337;File=inline=1;size=%d;width=%d;height=%d;preserveAspectRatio=1:%s\a",
+				buf.Len(), artCols, rows, base64.StdEncoding.EncodeToString(buf.Bytes())),
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown image protocol %q", protocol)
+}
+
+// scaleToWidth shrinks img to width pixels across, keeping its shape, by
+// averaging the pixels each new one covers. Narrower images are returned
+// as they are.
+func scaleToWidth(img image.Image, width int) image.Image {
+	b := img.Bounds()
+	if b.Dx() <= width {
+		return img
+	}
+	height := max(1, b.Dy()*width/b.Dx())
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		y0 := b.Min.Y + y*b.Dy()/height
+		y1 := max(y0+1, b.Min.Y+(y+1)*b.Dy()/height)
+		for x := range width {
+			x0 := b.Min.X + x*b.Dx()/width
+			x1 := max(x0+1, b.Min.X+(x+1)*b.Dx()/width)
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{uint16(r / n), uint16(g / n), uint16(bl / n), uint16(a / n)})
+		}
+	}
+	return dst
+}
+
+// drawArt shows the current station's artwork at the cursor and moves
+// below it. It draws nothing until the artwork has loaded, when the
+// terminal is too narrow for it, or while -blind hides the station.
+func (p *Player) drawArt(cols int) {
+	if (cols > 0 && cols < artCols+4) || p.hidden() {
+		return
+	}
+	p.mu.Lock()
+	art := p.art
+	var upload string
+	if art != nil && !p.artUploaded {
+		upload = art.upload
+		p.artUploaded = true
+	}
+	p.mu.Unlock()
+	if art != nil {
+		fmt.Print(upload + art.draw + "\r\n")
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// VolumePoint sets the volume at an hour of the day, in the config file's
+// auto_volume list for -auto-volume
+type VolumePoint struct {
+	Hour   int `json:"hour"`   // 0-23
+	Volume int `json:"volume"` // percent
+}
+
+// volumePoint is a checked VolumePoint
+type volumePoint struct {
+	minute int // minutes after midnight
+	volume int
+}
+
+// parseVolumeCurve checks the config file's auto_volume points and sorts
+// them by time. Points with problems are skipped and described in the
+// warnings.
+func parseVolumeCurve(points []VolumePoint, maxVolume int) ([]volumePoint, []string) {
+	var (
+		parsed   []volumePoint
+		warnings []string
+		seen     = map[int]bool{}
+	)
+	for i, pt := range points {
+		label := fmt.Sprintf("auto_volume point %d", i+1)
+		if pt.Hour < 0 || pt.Hour > 23 {
+			warnings = append(warnings, fmt.Sprintf("%s: hour %d is outside 0-23; skipped", label, pt.Hour))
+			continue
+		}
+		if pt.Volume < 0 || pt.Volume > maxVolume {
+			warnings = append(warnings, fmt.Sprintf("%s: volume %d is outside 0-%d; skipped", label, pt.Volume, maxVolume))
+			continue
+		}
+		if seen[pt.Hour] {
+			warnings = append(warnings, fmt.Sprintf("%s: another point already sets hour %d; skipped", label, pt.Hour))
+			continue
+		}
+		seen[pt.Hour] = true
+		parsed = append(parsed, volumePoint{minute: pt.Hour * 60, volume: pt.Volume})
+	}
+	sort.Slice(parsed, func(i, j int) bool { return parsed[i].minute < parsed[j].minute })
+	return parsed, warnings
+}
+
+// curveVolume returns the volume the curve gives at minute of the day,
+// interpolated in a straight line between the points around it. The
+// curve wraps around midnight, so the hours after the last point lead up
+// to the first.
+func curveVolume(curve []volumePoint, minute int) int {
+	prev, next := curve[len(curve)-1], curve[0]
+	prev.minute -= 24 * 60
+	next.minute += 24 * 60
+	for _, pt := range curve {
+		if pt.minute <= minute {
+			prev = pt
+		}
+	}
+	for i := len(curve) - 1; i >= 0; i-- {
+		if curve[i].minute > minute {
+			next = curve[i]
+		}
+	}
+	if next.minute == prev.minute {
+		return prev.volume
+	}
+	frac := float64(minute-prev.minute) / float64(next.minute-prev.minute)
+	return int(math.Round(float64(prev.volume) + frac*float64(next.volume-prev.volume)))
+}
+
+// isCurvePoint reports whether the curve has a point at minute
+func isCurvePoint(curve []volumePoint, minute int) bool {
+	for _, pt := range curve {
+		if pt.minute == minute {
+			return true
+		}
+	}
+	return false
+}
+
+// holdAutoVolume keeps -auto-volume from changing a volume the user has
+// just set, until the curve's next point
+func (p *Player) holdAutoVolume() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.autoCurve != nil {
+		p.volumeOverride = true
+	}
+}
+
+// globalVolume returns the volume for stations without their own: the
+// -auto-volume curve's, unless the user has overridden it, else the one
+// they set
+func (p *Player) globalVolume() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.autoCurve != nil && !p.volumeOverride {
+		return p.autoVolume
+	}
+	return p.baseVolume
+}
+
+// startAutoVolume turns -auto-volume on with curve, at the volume it gives
+// for now
+func (p *Player) startAutoVolume(curve []volumePoint, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.autoCurve = curve
+	p.autoVolume = curveVolume(curve, now.Hour()*60)
+}
+
+// runAutoVolume follows the -auto-volume curve hour by hour until ctx is
+// cancelled, calling apply with each new volume. A volume set by hand
+// holds until the curve's next point.
+func (p *Player) runAutoVolume(ctx context.Context, apply func(percent int)) {
+	for {
+		now := time.Now()
+		next := time.Date(now.Year(), now.Month(), now.Day(), now.Hour()+1, 0, 0, 0, now.Location())
+		if !waitUntil(ctx, next) {
+			return
+		}
+		minute := next.Hour() * 60
+		p.mu.Lock()
+		// The user's volume gives way at a point even when the curve's
+		// volume hasn't changed since they set it
+		released := p.volumeOverride && isCurvePoint(p.autoCurve, minute)
+		if released {
+			p.volumeOverride = false
+		}
+		target := curveVolume(p.autoCurve, minute)
+		changed := !p.volumeOverride && (released || target != p.autoVolume)
+		p.autoVolume = target
+		p.mu.Unlock()
+		if changed {
+			apply(target)
+		}
+	}
+}
+
+// setAutoVolume makes an -auto-volume change audible, the way the volume
+// command does, without saving it. A station with its own volume keeps
+// it.
+func (p *Player) setAutoVolume(percent int, url string) {
+	if p.stationVolume {
+		return
+	}
+	uiPrintf("\n🔉 Auto volume: %s\n", volumeText(percent))
+	if live := p.SetVolume(percent); !live && p.Playing() {
+		if err := p.Restart(url); err != nil {
+			reportStartError(err, p.Backend())
+		}
+	}
+}
+
+// describeAutoVolume says what -auto-volume starts at
+func describeAutoVolume(curve []volumePoint, now time.Time) string {
+	return fmt.Sprintf("%s now, following %d points through the day", volumeText(curveVolume(curve, now.Hour()*60)), len(curve))
+}
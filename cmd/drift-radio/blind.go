@@ -0,0 +1,34 @@
+package main
+
+// hiddenName stands in for the station's name while -blind hides it
+const hiddenName = "???"
+
+// hidden reports whether -blind is hiding the current station. Each
+// station stays hidden until the reveal command.
+func (p *Player) hidden() bool {
+	return p.blind && !p.revealed.Load()
+}
+
+// displayName returns the station name to show: the name, or hiddenName
+// while it's hidden
+func (p *Player) displayName(name string) string {
+	if p.hidden() {
+		return hiddenName
+	}
+	return name
+}
+
+// reveal shows the hidden station's name, and puts it and the track title
+// back on the status line and Discord
+func (p *Player) reveal(name string) {
+	wasHidden := p.hidden()
+	p.revealed.Store(true)
+	uiPrintln("🎵 Now Playing:", name)
+	if !wasHidden {
+		return
+	}
+	p.stateChanged(p.Status())
+	if title := p.Analyzer().GetStats().Title; p.presence != nil && title != "" {
+		p.presence.TitleChanged(title)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+
+	"github.com/hhaidrr/cli-radio-player/pkg/radio"
+)
+
+// browserCommand returns the command that opens url in the default
+// browser
+func browserCommand(url string) []string {
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{"open", url}
+	case "windows":
+		// cmd's start would split the URL at its & characters
+		return []string{"rundll32", "url.dll,FileProtocolHandler", url}
+	}
+	return []string{"xdg-open", url}
+}
+
+// openStation handles the open command: the station's page, such as a
+// YouTube stream with its chat, in the browser. Raw stream URLs have no
+// page to show, so they're left alone.
+func openStation(station radio.Station) {
+	if !radio.NeedsResolution(station.URL) {
+		fmt.Println("This station is a raw stream, not a web page; there's nothing to open")
+		return
+	}
+	args := browserCommand(station.URL)
+	cmd := exec.Command(args[0], args[1:]...)
+	if err := cmd.Start(); err != nil {
+		fmt.Printf("Could not open a browser (%s: %v); the station URL is:\n%s\n", args[0], err, station.URL)
+		return
+	}
+	// xdg-open and open hand the URL over and exit; reap them
+	go func() { _ = cmd.Wait() }()
+	uiPrintf("🌐 Opened %s in your browser\n", radio.RedactURL(station.URL))
+}
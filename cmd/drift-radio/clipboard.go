@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/hhaidrr/cli-radio-player/pkg/radio"
+)
+
+// clipboardTimeout bounds a clipboard tool that hangs, such as xclip with
+// no X server to talk to
+const clipboardTimeout = 5 * time.Second
+
+// errNoClipboard means none of the platform's clipboard tools is installed
+var errNoClipboard = errors.New("no clipboard tool found")
+
+// clipboardTools lists the commands that take text on stdin and put it on
+// the clipboard, in the order they're tried
+func clipboardTools() [][]string {
+	switch runtime.GOOS {
+	case "darwin":
+		return [][]string{{"pbcopy"}}
+	case "windows":
+		return [][]string{{"clip"}}
+	}
+	tools := [][]string{
+		{"xclip", "-selection", "clipboard"},
+		{"xsel", "--clipboard", "--input"},
+	}
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		tools = append([][]string{{"wl-copy"}}, tools...)
+	}
+	return tools
+}
+
+// copyToClipboard puts text on the system clipboard with the first tool
+// that's installed and returns its name, or errNoClipboard
+func copyToClipboard(text string) (string, error) {
+	for _, tool := range clipboardTools() {
+		path, err := exec.LookPath(tool[0])
+		if err != nil {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), clipboardTimeout)
+		cmd := exec.CommandContext(ctx, path, tool[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		// xclip and wl-copy stay behind to serve the selection, so their
+		// output isn't captured; waiting on it would wait on them
+		cmd.WaitDelay = time.Second
+		err = cmd.Run()
+		cancel()
+		return tool[0], err
+	}
+	return "", errNoClipboard
+}
+
+// copyURL handles the copy command: the station's URL, or with "resolved"
+// the media URL it resolved to. Without a clipboard tool the URL is
+// printed so it can be copied by hand.
+func copyURL(p *Player, station radio.Station, arg string) {
+	what, text := "station URL", station.URL
+	switch arg {
+	case "", "url":
+	case "resolved":
+		what, text = "resolved URL", p.Status().ResolvedURL
+		if text == "" {
+			fmt.Println("The stream hasn't been resolved yet; play it first")
+			return
+		}
+	default:
+		fmt.Printf("Usage: %[1]s | %[1]s resolved\n", p.keys.key("copy"))
+		return
+	}
+	tool, err := copyToClipboard(text)
+	switch {
+	case errors.Is(err, errNoClipboard):
+		fmt.Printf("No clipboard tool found (%s); the %s is:\n%s\n", clipboardHint(), what, text)
+	case err != nil:
+		fmt.Printf("Copy failed (%s: %v); the %s is:\n%s\n", tool, err, what, text)
+	default:
+		uiPrintf("📋 Copied the %s: %s\n", what, radio.RedactURL(text))
+	}
+}
+
+// clipboardHint names the tools copyToClipboard looks for
+func clipboardHint() string {
+	var names []string
+	for _, tool := range clipboardTools() {
+		names = append(names, tool[0])
+	}
+	return "looked for " + strings.Join(names, ", ")
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hhaidrr/cli-radio-player/pkg/radio"
+)
+
+// Config is the user's configuration file
+type Config struct {
+	Stations           []radio.Station `json:"stations"`
+	LastFM             LastFMConfig    `json:"lastfm"`
+	Discord            DiscordConfig   `json:"discord"`
+	Cookies            string          `json:"cookies"`              // yt-dlp --cookies file
+	CookiesFromBrowser string          `json:"cookies_from_browser"` // yt-dlp --cookies-from-browser
+	Schedule           []ScheduleRule  `json:"schedule"`             // stations by time of day, for -schedule
+	AutoVolume         []VolumePoint   `json:"auto_volume"`          // volume by time of day, for -auto-volume
+
+	Keybindings map[string]keyList `json:"keybindings"` // keys by interactive command, replacing its defaults
+}
+
+// defaultConfigPath returns where the config file lives when --config
+// isn't given
+func defaultConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "drift-radio", "config.json"), nil
+}
+
+// loadConfig reads and validates the config file. Stations with problems
+// are skipped and described in the returned warnings; the error is only
+// set when the file can't be used at all.
+func loadConfig(path string) (Config, []string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, nil, err
+	}
+	return parseConfig(data, path)
+}
+
+// parseConfig is loadConfig for data already read from source, a path or
+// URL used in messages
+func parseConfig(data []byte, source string) (Config, []string, error) {
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, nil, fmt.Errorf("parse %s: %w", source, err)
+	}
+
+	var warnings []string
+	stations := make([]radio.Station, 0, len(cfg.Stations))
+	for i, s := range cfg.Stations {
+		s.Name = strings.TrimSpace(s.Name)
+		label := fmt.Sprintf("station %d", i+1)
+		if s.Name != "" {
+			label += fmt.Sprintf(" (%s)", s.Name)
+		}
+		// A station given only as mirrors plays the first one first
+		if strings.TrimSpace(s.URL) == "" && len(s.URLs) > 0 {
+			s.URL, s.URLs = s.URLs[0], s.URLs[1:]
+		}
+		normalized, urlWarnings, err := radio.NormalizeStationURL(s.URL)
+		for _, w := range urlWarnings {
+			warnings = append(warnings, fmt.Sprintf("%s: %s", label, w))
+		}
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("%s: %v; skipped", label, err))
+			continue
+		}
+		s.URL = normalized
+		var mirrors []string
+		for j, u := range s.URLs {
+			normalized, urlWarnings, err := radio.NormalizeStationURL(u)
+			for _, w := range urlWarnings {
+				warnings = append(warnings, fmt.Sprintf("%s: mirror %d: %s", label, j+1, w))
+			}
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("%s: mirror %d: %v; skipped", label, j+1, err))
+				continue
+			}
+			mirrors = append(mirrors, normalized)
+		}
+		s.URLs = mirrors
+		for _, w := range radio.ValidateHeaders(s.Headers) {
+			warnings = append(warnings, fmt.Sprintf("%s: %s", label, w))
+		}
+		if s.Auth != nil && s.Auth.Username == "" {
+			warnings = append(warnings, fmt.Sprintf("%s: auth has no username; ignored", label))
+			s.Auth = nil
+		}
+		// Past 100 takes -allow-boost too; without it the station plays at 100
+		if s.Volume != nil && (*s.Volume < 0 || *s.Volume > radio.MaxBoostVolume) {
+			warnings = append(warnings, fmt.Sprintf("%s: volume %d is outside 0-%d; using the global volume", label, *s.Volume, radio.MaxBoostVolume))
+			s.Volume = nil
+		}
+		if s.Name == "" {
+			s.Name = s.URL
+		}
+		stations = append(stations, s)
+	}
+	if len(stations) == 0 {
+		return cfg, warnings, fmt.Errorf("%s has no usable stations", source)
+	}
+	cfg.Stations = stations
+	return cfg, warnings, nil
+}
+
+// exampleConfig is the file -init-config writes: the built-in stations,
+// with the rest of the schema shown by example under keys the loader
+// ignores. JSON has no comments, so the notes are "_comment" keys.
+type exampleConfig struct {
+	Comment            string          `json:"_comment"`
+	Stations           []radio.Station `json:"stations"`
+	ExampleStation     exampleStation  `json:"_example_station"`
+	ExampleSchedule    []ScheduleRule  `json:"_example_schedule"`
+	ExampleKeybindings map[string]any  `json:"_example_keybindings"`
+}
+
+// exampleStation is a station with a note on its settings
+type exampleStation struct {
+	radio.Station
+	Comment string `json:"_comment"`
+}
+
+// newExampleConfig returns the example config for -init-config
+func newExampleConfig() exampleConfig {
+	volume := 60
+	return exampleConfig{
+		Comment:  "drift-radio stations and settings. Keys starting with _ are notes and examples, and are ignored; to use an example, copy it without the _. The README describes every setting.",
+		Stations: defaultStations,
+		ExampleStation: exampleStation{
+			Station: radio.Station{
+				Name:        "My Icecast station",
+				URL:         "https://stream.example.com/live.mp3",
+				Description: "Shown in the station list",
+				Volume:      &volume,
+				Headers:     map[string]string{"Referer": "https://example.com/"},
+				URLs:        []string{"https://backup.example.com/live.mp3"},
+				Artwork:     "https://example.com/logo.png",
+			},
+			Comment: "Add stations like this one to the stations list. Only name and url are needed. volume (0-100) replaces the global volume on this station, headers are sent with its requests, urls are mirrors tried in order when url fails, and artwork is shown with -art.",
+		},
+		ExampleSchedule: []ScheduleRule{
+			{After: "08:00", Station: 1},
+			{After: "22:00", Station: 5},
+		},
+		ExampleKeybindings: map[string]any{
+			"next": []string{"k", "next"},
+			"prev": "j",
+		},
+	}
+}
+
+// writeExampleConfig writes the example config to path, refusing to
+// replace a file that's there unless force is set
+func writeExampleConfig(path string, force bool) error {
+	if _, err := os.Stat(path); err == nil && !force {
+		return fmt.Errorf("%s already exists; add -force to replace it", path)
+	}
+	data, err := json.MarshalIndent(newExampleConfig(), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
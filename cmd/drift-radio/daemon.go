@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hhaidrr/cli-radio-player/pkg/radio"
+)
+
+// defaultSocketPath returns where the daemon's control socket lives
+func defaultSocketPath() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "drift-radio.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("drift-radio-%d.sock", os.Getuid()))
+}
+
+// daemon runs the player headless and takes commands over a Unix socket.
+// The protocol is one command line per connection; the reply is written
+// back and the connection closed.
+type daemon struct {
+	mu       sync.Mutex // serializes commands
+	p        *Player
+	stations []radio.Station
+	quit     context.CancelFunc
+}
+
+// runDaemon starts the given station and serves control commands on
+// socketPath until ctx is cancelled or a client sends quit
+func runDaemon(ctx context.Context, p *Player, stations []radio.Station, startIdx int, socketPath string) error {
+	// Clear out a socket left behind by a daemon that didn't shut down cleanly
+	if conn, err := net.DialTimeout("unix", socketPath, time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("a daemon is already listening on %s", socketPath)
+	}
+	_ = os.Remove(socketPath)
+
+	ln, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(socketPath)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+
+	slog.Info("daemon listening", "socket", socketPath)
+	d := &daemon{p: p, stations: stations, quit: cancel}
+	p.OnUnreachable(func(string) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		p.unreachable(stations, func(idx int) { fmt.Println(d.play(idx)) })
+	})
+	p.currentStation = startIdx
+	p.applyStation(stations[startIdx])
+	if err := p.Start(stations[startIdx].URL); err != nil {
+		slog.Error("failed to start", "station", stations[startIdx].Name, "err", err, "hint", radio.ResolveHint(err))
+	}
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				_ = p.Stop()
+				return nil
+			}
+			return err
+		}
+		go d.serve(conn)
+	}
+}
+
+func (d *daemon) serve(conn net.Conn) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(30 * time.Second))
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return
+	}
+	reply := d.handle(strings.TrimSpace(line))
+	fmt.Fprintln(conn, reply)
+}
+
+// handle runs one control command and returns the reply text
+func (d *daemon) handle(line string) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	p := d.p
+
+	cmd, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+	switch cmd {
+	case "play":
+		idx := p.currentStation
+		if arg != "" {
+			n, err := strconv.Atoi(arg)
+			if err != nil || n < 1 || n > len(d.stations) {
+				return fmt.Sprintf("error: station must be 1-%d", len(d.stations))
+			}
+			idx = n - 1
+		}
+		return d.play(idx)
+	case "next":
+		return d.play((p.currentStation + 1) % len(d.stations))
+	case "stop":
+		if err := p.Stop(); err != nil {
+			return "error: " + err.Error()
+		}
+		return "stopped"
+	case "vol":
+		v, err := strconv.Atoi(arg)
+		if err != nil {
+			return fmt.Sprintf("error: usage: vol <0-%d>", p.MaxVolume())
+		}
+		live := p.setUserVolume(v)
+		p.persistState()
+		if !live && p.Playing() {
+			if err := p.Restart(d.stations[p.currentStation].URL); err != nil {
+				return "error: " + err.Error()
+			}
+		}
+		return "volume " + volumeText(p.Volume())
+	case "status":
+		var b strings.Builder
+		printStatus(&b, p, d.stations[p.currentStation])
+		return strings.TrimRight(b.String(), "\n")
+	case "quit":
+		_ = p.Stop()
+		d.quit()
+		return "bye"
+	case "":
+		return "error: empty command"
+	default:
+		return fmt.Sprintf("error: unknown command %q (play N, next, stop, vol N, status, quit)", cmd)
+	}
+}
+
+func (d *daemon) play(idx int) string {
+	p := d.p
+	p.currentStation = idx
+	p.persistState()
+	st := d.stations[idx]
+	p.applyStation(st)
+	if err := p.Restart(st.URL); err != nil {
+		return "error: " + err.Error()
+	}
+	return "playing: " + st.Name
+}
+
+// sendControl sends one command to a running daemon and prints the reply
+func sendControl(socketPath, command string) error {
+	conn, err := net.DialTimeout("unix", socketPath, 2*time.Second)
+	if err != nil {
+		return fmt.Errorf("no daemon on %s: %w", socketPath, err)
+	}
+	defer conn.Close()
+	// Starting a YouTube station can take a while to resolve
+	_ = conn.SetDeadline(time.Now().Add(60 * time.Second))
+	if _, err := fmt.Fprintln(conn, command); err != nil {
+		return err
+	}
+	reply, err := io.ReadAll(conn)
+	if err != nil {
+		return err
+	}
+	fmt.Print(string(reply))
+	if strings.HasPrefix(string(reply), "error:") {
+		return errors.New("command failed")
+	}
+	return nil
+}
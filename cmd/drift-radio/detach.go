@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hhaidrr/cli-radio-player/pkg/radio"
+)
+
+// detachedPlayer records a player left running by -detach, for -stop
+type detachedPlayer struct {
+	PID     int    `json:"pid"`
+	Backend string `json:"backend"`
+	Station string `json:"station"`
+}
+
+// detachedPath returns where the detached player's PID file lives, next
+// to the daemon's socket
+func detachedPath() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "drift-radio.pid")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("drift-radio-%d.pid", os.Getuid()))
+}
+
+// loadDetached reads the PID file. The boolean is false when no player
+// has been left running.
+func loadDetached() (detachedPlayer, bool, error) {
+	var d detachedPlayer
+	data, err := os.ReadFile(detachedPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return d, false, nil
+	}
+	if err != nil {
+		return d, false, err
+	}
+	if err := json.Unmarshal(data, &d); err != nil {
+		return d, false, err
+	}
+	return d, true, nil
+}
+
+// stopDetached stops the player an earlier -detach session left running
+// and removes its PID file. It reports whether there was one to stop.
+func stopDetached() (detachedPlayer, bool, error) {
+	d, ok, err := loadDetached()
+	if err != nil || !ok {
+		return d, false, err
+	}
+	err = radio.StopDetached(d.PID, d.Backend)
+	if err != nil && !errors.Is(err, radio.ErrDetachedGone) {
+		return d, false, err
+	}
+	_ = os.Remove(detachedPath())
+	return d, err == nil, nil
+}
+
+// detachOnQuit leaves the stream playing when quitting with -detach and
+// saves its PID for -stop. It reports whether it did; otherwise the
+// caller stops the stream as usual.
+func (p *Player) detachOnQuit() bool {
+	if !p.detach {
+		return false
+	}
+	name := p.Status().Name
+	pid, err := p.Detach()
+	if err != nil {
+		return false
+	}
+	d := detachedPlayer{PID: pid, Backend: p.Backend(), Station: name}
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err == nil {
+		err = os.WriteFile(detachedPath(), data, 0o644)
+	}
+	if err != nil {
+		slog.Warn("could not save the detached player's PID", "err", err)
+		fmt.Printf("%s keeps playing (pid %d)\n", name, pid)
+		return true
+	}
+	fmt.Printf("%s keeps playing in the background; run drift-radio -stop to stop it\n", name)
+	return true
+}
+
+// quit ends playback on the way out: stopped, or left playing with
+// -detach. It sums up the session last.
+func (p *Player) quit() {
+	if !p.detachOnQuit() {
+		_ = p.Stop()
+	}
+	p.session.printSummary(time.Since(p.sessionStart))
+}
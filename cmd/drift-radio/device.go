@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hhaidrr/cli-radio-player/pkg/radio"
+)
+
+// printAudioDevices lists devices for -list-devices
+func printAudioDevices(devices []radio.AudioDevice, current string) {
+	if len(devices) == 0 {
+		fmt.Println("No audio output devices found")
+		return
+	}
+	fmt.Println("Audio output devices (pass the name to -device):")
+	for _, d := range devices {
+		mark := " "
+		if d.Name == current {
+			mark = "*"
+		}
+		if d.Description != "" {
+			fmt.Printf(" %s %s\n     %s\n", mark, d.Name, d.Description)
+		} else {
+			fmt.Printf(" %s %s\n", mark, d.Name)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hhaidrr/cli-radio-player/pkg/radio"
+)
+
+const (
+	// Discord IPC frame opcodes
+	discordOpHandshake = 0
+	discordOpFrame     = 1
+	discordOpClose     = 2
+	discordOpPing      = 3
+	discordOpPong      = 4
+
+	// Discord allows 5 activity updates per 20 seconds
+	discordMinInterval = 5 * time.Second
+	discordTimeout     = 3 * time.Second
+	// Activity text fields are limited to 128 characters
+	discordMaxText = 128
+)
+
+// DiscordConfig holds the Discord application whose name heads the
+// Rich Presence card
+type DiscordConfig struct {
+	ClientID string `json:"client_id"`
+}
+
+// DiscordPresence shows the playing station and track as the user's
+// Discord activity. Updates are sent from a background goroutine, at most
+// one per discordMinInterval with only the latest state sent, and every
+// failure is silent: when Discord isn't running, nothing happens.
+type DiscordPresence struct {
+	clientID string
+	wake     chan struct{}
+	done     chan struct{}
+	stopped  chan struct{}
+	closing  sync.Once
+
+	mu      sync.Mutex
+	playing bool
+	station string
+	title   string
+	since   time.Time
+
+	// Owned by the run goroutine
+	conn  net.Conn
+	nonce int
+}
+
+// NewDiscordPresence checks the config and starts the update goroutine
+func NewDiscordPresence(cfg DiscordConfig) (*DiscordPresence, error) {
+	if cfg.ClientID == "" {
+		return nil, errors.New("discord config needs client_id (an application ID from the Discord developer portal)")
+	}
+	d := &DiscordPresence{
+		clientID: cfg.ClientID,
+		wake:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	go d.run()
+	return d, nil
+}
+
+// Playing shows station as playing since the given time. A new station
+// drops the previous station's track title.
+func (d *DiscordPresence) Playing(station string, since time.Time) {
+	d.mu.Lock()
+	if station != d.station {
+		d.title = ""
+	}
+	d.playing, d.station, d.since = true, station, since
+	d.mu.Unlock()
+	d.notify()
+}
+
+// Stopped clears the activity
+func (d *DiscordPresence) Stopped() {
+	d.mu.Lock()
+	d.playing, d.title = false, ""
+	d.mu.Unlock()
+	d.notify()
+}
+
+// TitleChanged shows the stream's current track title
+func (d *DiscordPresence) TitleChanged(title string) {
+	d.mu.Lock()
+	d.title = title
+	d.mu.Unlock()
+	d.notify()
+}
+
+// Close clears the activity and disconnects from Discord
+func (d *DiscordPresence) Close() {
+	d.closing.Do(func() { close(d.done) })
+	<-d.stopped
+}
+
+func (d *DiscordPresence) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (d *DiscordPresence) run() {
+	defer close(d.stopped)
+	defer func() {
+		if d.conn != nil {
+			_ = d.setActivity(nil)
+			d.conn.Close()
+		}
+	}()
+
+	var last time.Time
+	for {
+		select {
+		case <-d.done:
+			return
+		case <-d.wake:
+		}
+		if wait := time.Until(last.Add(discordMinInterval)); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-d.done:
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+		last = time.Now()
+
+		d.mu.Lock()
+		var activity map[string]any
+		if d.playing {
+			activity = map[string]any{
+				"type":                2, // Listening
+				"status_display_type": 2, // "Listening to <details>"
+				"details":             truncateRunes(d.station, discordMaxText),
+				"timestamps":          map[string]int64{"start": d.since.Unix()},
+			}
+			if d.title != "" {
+				activity["state"] = truncateRunes(d.title, discordMaxText)
+			}
+		}
+		d.mu.Unlock()
+
+		if d.conn == nil {
+			if d.conn = d.connect(); d.conn == nil {
+				continue
+			}
+		}
+		if err := d.setActivity(activity); err != nil {
+			slog.Debug("Discord activity update failed; reconnecting", "err", err)
+			d.conn.Close()
+			d.conn = nil
+		}
+	}
+}
+
+// connect dials Discord's IPC socket and completes the handshake,
+// returning nil when Discord isn't reachable
+func (d *DiscordPresence) connect() net.Conn {
+	for _, path := range discordSocketPaths() {
+		conn, err := radio.DialIPC(path, discordTimeout)
+		if err != nil {
+			continue
+		}
+		d.conn = conn
+		hello := map[string]any{"v": 1, "client_id": d.clientID}
+		if err := d.send(discordOpHandshake, hello); err != nil {
+			conn.Close()
+			d.conn = nil
+			continue
+		}
+		return conn
+	}
+	return nil
+}
+
+// discordSocketPaths lists where Discord may have put its IPC socket,
+// including the Flatpak and Snap locations
+func discordSocketPaths() []string {
+	var dirs []string
+	for _, env := range []string{"XDG_RUNTIME_DIR", "TMPDIR", "TMP", "TEMP"} {
+		if dir := os.Getenv(env); dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	dirs = append(dirs, "/tmp")
+
+	var paths []string
+	for _, dir := range dirs {
+		for _, sub := range []string{"", "app/com.discordapp.Discord", "snap.discord"} {
+			for i := range 10 {
+				paths = append(paths, filepath.Join(dir, sub, "discord-ipc-"+strconv.Itoa(i)))
+			}
+		}
+	}
+	return paths
+}
+
+// setActivity sends SET_ACTIVITY; a nil activity clears it
+func (d *DiscordPresence) setActivity(activity map[string]any) error {
+	d.nonce++
+	args := map[string]any{"pid": os.Getpid()}
+	if activity != nil {
+		args["activity"] = activity
+	}
+	return d.send(discordOpFrame, map[string]any{
+		"cmd":   "SET_ACTIVITY",
+		"args":  args,
+		"nonce": strconv.Itoa(d.nonce),
+	})
+}
+
+// send writes a frame and reads Discord's reply, answering pings on the way
+func (d *DiscordPresence) send(op uint32, payload any) error {
+	if err := d.conn.SetDeadline(time.Now().Add(discordTimeout)); err != nil {
+		return err
+	}
+	if err := writeDiscordFrame(d.conn, op, payload); err != nil {
+		return err
+	}
+	for {
+		op, data, err := readDiscordFrame(d.conn)
+		if err != nil {
+			return err
+		}
+		switch op {
+		case discordOpFrame:
+			return nil
+		case discordOpPing:
+			if err := writeDiscordFrame(d.conn, discordOpPong, json.RawMessage(data)); err != nil {
+				return err
+			}
+		case discordOpClose:
+			return fmt.Errorf("discord closed the connection: %s", data)
+		}
+	}
+}
+
+// writeDiscordFrame writes an IPC frame: little-endian opcode and length,
+// then the JSON payload
+func writeDiscordFrame(w io.Writer, op uint32, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	frame := make([]byte, 8+len(data))
+	binary.LittleEndian.PutUint32(frame[0:], op)
+	binary.LittleEndian.PutUint32(frame[4:], uint32(len(data)))
+	copy(frame[8:], data)
+	_, err = w.Write(frame)
+	return err
+}
+
+func readDiscordFrame(r io.Reader) (uint32, []byte, error) {
+	var header [8]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	n := binary.LittleEndian.Uint32(header[4:])
+	if n > 1<<20 {
+		return 0, nil, fmt.Errorf("discord frame too large (%d bytes)", n)
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, nil, err
+	}
+	return binary.LittleEndian.Uint32(header[0:]), data, nil
+}
+
+// truncateRunes shortens s to at most n characters
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
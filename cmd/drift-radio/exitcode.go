@@ -0,0 +1,26 @@
+package main
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// Exit codes, for scripts and supervisors that check how drift-radio ended
+const (
+	exitOK          = 0 // finished, or quit with q
+	exitError       = 1 // anything else, such as a bad flag
+	exitDependency  = 2 // ffplay, mpv, or yt-dlp is missing
+	exitUnreachable = 3 // a stream couldn't be resolved, started, or reached
+	exitConfig      = 4 // the config file can't be used
+	exitInterrupted = 5 // stopped by Ctrl+C or a signal
+)
+
+// startExitCode picks the exit code for a failed Start: a missing program
+// is a dependency problem, anything else means the stream couldn't be
+// played
+func startExitCode(err error) int {
+	if errors.Is(err, exec.ErrNotFound) {
+		return exitDependency
+	}
+	return exitUnreachable
+}
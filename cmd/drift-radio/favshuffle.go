@@ -0,0 +1,54 @@
+package main
+
+import (
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/hhaidrr/cli-radio-player/pkg/radio"
+)
+
+// favoriteRating is the fewest stars that make a station one of the
+// favorites -shuffle-favs cycles through
+const favoriteRating = 4
+
+// favoriteStations returns the indexes of the favorite stations other
+// than current, in list order
+func favoriteStations(stations []radio.Station, current int, ratings map[string]int) []int {
+	var favs []int
+	for i, s := range stations {
+		if i != current && ratings[s.URL] >= favoriteRating {
+			favs = append(favs, i)
+		}
+	}
+	return favs
+}
+
+// nextFavorite picks a random favorite station other than current for
+// the favorites shuffle. Each candidate gets a quick health check first;
+// those that fail are skipped and logged. It's false when no favorite
+// passes.
+func (p *Player) nextFavorite(stations []radio.Station, current int) (int, bool) {
+	favs := favoriteStations(stations, current, p.savedRatings())
+	rand.Shuffle(len(favs), func(i, j int) { favs[i], favs[j] = favs[j], favs[i] })
+	for _, i := range favs {
+		check := radio.CheckStations(stations[i:i+1], p.Analyzer().Client(), p.ResolveOptions())[0]
+		if check.OK {
+			return i, true
+		}
+		slog.Info("shuffle: skipping a favorite that failed its health check", "station", p.displayName(stations[i].Name), "status", check.Status)
+	}
+	return 0, false
+}
+
+// setShuffleFavs turns the favorites shuffle on every d, or off for 0, as
+// it's resumed on the next run
+func (p *Player) setShuffleFavs(d time.Duration) {
+	p.mu.Lock()
+	changed := d != p.shuffleFavs
+	p.shuffleFavs = d
+	p.mu.Unlock()
+	if changed {
+		p.persistState()
+	}
+}
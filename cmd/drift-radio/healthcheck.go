@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hhaidrr/cli-radio-player/pkg/radio"
+)
+
+// printStationChecks prints the results as a table and reports whether
+// every station passed
+func printStationChecks(results []radio.StationCheck) bool {
+	allOK := true
+	fmt.Printf("  %-3s %-4s %-8s %-40s %s\n", "#", "", "LATENCY", "STATION", "STATUS")
+	for i, r := range results {
+		mark := "OK"
+		if !r.OK {
+			mark = "FAIL"
+			allOK = false
+		}
+		name := r.Station.Name
+		if len(name) > 40 {
+			name = name[:37] + "..."
+		}
+		fmt.Printf("  %-3d %-4s %-8s %-40s %s\n", i+1, mark, r.Latency.Round(time.Millisecond), name, r.Status)
+	}
+	return allOK
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// historySize is how many entered commands are kept for recall
+const historySize = 100
+
+// history is a bounded list of entered commands, oldest first
+type history struct {
+	entries []string
+}
+
+// Add records a command, skipping blanks and an immediate repeat. Once
+// full, the oldest entry is dropped.
+func (h *history) Add(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" || (len(h.entries) > 0 && h.entries[len(h.entries)-1] == line) {
+		return
+	}
+	if len(h.entries) == historySize {
+		copy(h.entries, h.entries[1:])
+		h.entries[len(h.entries)-1] = line
+		return
+	}
+	h.entries = append(h.entries, line)
+}
+
+// historyPath returns the location of the saved command history
+func historyPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "drift-radio", "history"), nil
+}
+
+// loadHistory reads saved commands, one per line. A missing file is an
+// empty history.
+func loadHistory(path string) (history, error) {
+	var h history
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return h, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		h.Add(line)
+	}
+	return h, nil
+}
+
+// saveHistory writes the commands to path, creating its directory if needed
+func saveHistory(path string, h history) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	var b strings.Builder
+	for _, line := range h.entries {
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	return os.WriteFile(path, []byte(b.String()), 0o600)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// httpShutdownTimeout bounds how long the -http-addr server waits for
+// requests in flight when the program quits
+const httpShutdownTimeout = 2 * time.Second
+
+// httpListenAddr returns the address to serve -http-addr on. A bare port
+// such as ":8080" binds to localhost only; reaching the player from other
+// machines takes an explicit host.
+func httpListenAddr(addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", err
+	}
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// nowPlaying is what /nowplaying serves
+type nowPlaying struct {
+	Station string `json:"station,omitempty"`
+	Track   string `json:"track,omitempty"` // file name in a directory station
+	Title   string `json:"title,omitempty"` // from the stream's metadata
+	Playing bool   `json:"playing"`
+	Paused  bool   `json:"paused,omitempty"`
+	Volume  int    `json:"volume"`
+}
+
+// serveHTTP serves the health and status endpoints on addr until ctx is
+// cancelled:
+//
+//	/healthz     200 while a stream is playing, 503 otherwise
+//	/stats       the analyzer's stats as JSON
+//	/nowplaying  the station, track, and volume as JSON
+func serveHTTP(ctx context.Context, p *Player, addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		if !p.Playing() {
+			http.Error(w, "not playing", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok\n"))
+	})
+	mux.HandleFunc("GET /stats", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, p.Analyzer().GetStats())
+	})
+	mux.HandleFunc("GET /nowplaying", func(w http.ResponseWriter, r *http.Request) {
+		st := p.Status()
+		np := nowPlaying{
+			Station: p.displayName(st.Name),
+			Track:   st.Track,
+			Playing: st.Playing,
+			Paused:  st.Paused,
+			Volume:  st.Volume,
+		}
+		if st.Playing && !p.hidden() {
+			np.Title = p.Analyzer().GetStats().Title
+		}
+		writeJSON(w, np)
+	})
+
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), httpShutdownTimeout)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	slog.Info("http server listening", "addr", ln.Addr().String())
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Warn("http server stopped", "err", err)
+		}
+	}()
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(append(data, '\n'))
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// commandInput supplies the interactive loop's input lines
+type commandInput interface {
+	// Next returns the next input line, or ctx's error as soon as it's done
+	Next(ctx context.Context) (string, error)
+	// Remember adds an entered command to the recall history
+	Remember(line string)
+	// Close restores the terminal and saves history, if any
+	Close()
+}
+
+// newCommandInput returns a line editor with history recall when stdin
+// is a terminal that supports it, and a plain line reader otherwise (pipes,
+// Windows). A non-empty historyFile is loaded now and saved on Close.
+func newCommandInput(historyFile string) commandInput {
+	if e, err := newLineEditor(os.Stdin, historyFile); err == nil {
+		return e
+	}
+	return newLineReader(os.Stdin)
+}
+
+// lineReader reads lines on a background goroutine so a blocked read can
+// be abandoned when the context is cancelled (e.g. by Ctrl+C)
+type lineReader struct {
+	lines chan lineResult
+}
+
+type lineResult struct {
+	line string
+	err  error
+}
+
+func newLineReader(r io.Reader) *lineReader {
+	lr := &lineReader{lines: make(chan lineResult)}
+	go func() {
+		reader := bufio.NewReader(r)
+		for {
+			line, err := reader.ReadString('\n')
+			lr.lines <- lineResult{line, err}
+			if err != nil {
+				close(lr.lines)
+				return
+			}
+		}
+	}()
+	return lr
+}
+
+// Next returns the next input line, or ctx's error as soon as it's done
+func (lr *lineReader) Next(ctx context.Context) (string, error) {
+	return nextLine(ctx, lr.lines)
+}
+
+// Remember does nothing: without key-by-key input there's no recall
+func (lr *lineReader) Remember(string) {}
+
+func (lr *lineReader) Close() {}
+
+func nextLine(ctx context.Context, lines <-chan lineResult) (string, error) {
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case r, ok := <-lines:
+		if !ok {
+			return "", io.EOF
+		}
+		return r.line, r.err
+	}
+}
+
+// lineEditor reads the terminal key by key, echoing and editing the line
+// itself so the up/down arrows can recall earlier commands like a shell
+type lineEditor struct {
+	lines       chan lineResult
+	restore     func()
+	historyFile string
+
+	mu      sync.Mutex
+	history history
+}
+
+func newLineEditor(tty *os.File, historyFile string) (*lineEditor, error) {
+	restore, err := enableKeyInput(tty.Fd())
+	if err != nil {
+		return nil, err
+	}
+	e := &lineEditor{lines: make(chan lineResult), restore: restore, historyFile: historyFile}
+	if historyFile != "" {
+		if e.history, err = loadHistory(historyFile); err != nil {
+			slog.Warn("could not load command history", "err", err)
+		}
+	}
+	go e.run(tty)
+	return e, nil
+}
+
+func (e *lineEditor) Next(ctx context.Context) (string, error) {
+	return nextLine(ctx, e.lines)
+}
+
+func (e *lineEditor) Remember(line string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.history.Add(line)
+}
+
+func (e *lineEditor) Close() {
+	e.restore()
+	if e.historyFile == "" {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := saveHistory(e.historyFile, e.history); err != nil {
+		slog.Warn("could not save command history", "err", err)
+	}
+}
+
+func (e *lineEditor) run(r io.Reader) {
+	defer close(e.lines)
+	reader := bufio.NewReader(r)
+	var (
+		buf   []rune
+		draft []rune // the unsent line while browsing history
+		pos   = -1   // history position; -1 means not browsing
+	)
+	// replace swaps the displayed line for s by backing over the old text
+	replace := func(s []rune) {
+		if len(buf) > 0 {
+			fmt.Printf("\033[%dD\033[K", len(buf))
+		}
+		buf = append([]rune(nil), s...)
+		fmt.Print(string(buf))
+	}
+	// recall moves through history by delta, returning to the draft line
+	// when it steps past the newest entry
+	recall := func(delta int) {
+		e.mu.Lock()
+		entries := append([]string(nil), e.history.entries...)
+		e.mu.Unlock()
+		n := len(entries)
+		if pos < 0 {
+			pos, draft = n, buf
+		}
+		next := max(0, min(pos+delta, n))
+		if next == pos {
+			return
+		}
+		pos = next
+		if pos == n {
+			replace(draft)
+		} else {
+			replace([]rune(entries[pos]))
+		}
+	}
+
+	for {
+		c, _, err := reader.ReadRune()
+		if err != nil {
+			e.lines <- lineResult{string(buf), err}
+			return
+		}
+		switch c {
+		case '\r', '\n':
+			fmt.Println()
+			e.lines <- lineResult{string(buf) + "\n", nil}
+			buf, draft, pos = nil, nil, -1
+		case 0x7f, '\b':
+			if len(buf) > 0 {
+				buf = buf[:len(buf)-1]
+				fmt.Print("\b \b")
+			}
+		case 0x15: // Ctrl+U clears the line
+			replace(nil)
+		case 0x04: // Ctrl+D on an empty line ends input, like a shell
+			if len(buf) == 0 {
+				e.lines <- lineResult{"", io.EOF}
+				return
+			}
+		case 0x1b:
+			// Arrow keys send ESC [ A-D (or ESC O A-D); other sequences
+			// (Home, Delete, ...) are read in full and ignored
+			if c, _, err = reader.ReadRune(); err != nil || (c != '[' && c != 'O') {
+				continue
+			}
+			c, _, err = reader.ReadRune()
+			for err == nil && strings.ContainsRune("0123456789;", c) {
+				c, _, err = reader.ReadRune()
+			}
+			switch c {
+			case 'A':
+				recall(-1)
+			case 'B':
+				recall(1)
+			}
+		default:
+			if unicode.IsPrint(c) {
+				buf = append(buf, c)
+				fmt.Print(string(c))
+			}
+		}
+	}
+}
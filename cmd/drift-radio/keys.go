@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// spaceKey is how a binding names the space bar: a line that's only
+// spaces, since the prompt trims them
+const spaceKey = "space"
+
+// keyAction is an interactive command and the keys that run it by default
+type keyAction struct {
+	name string // what the config file's keybindings call it
+	keys []string
+}
+
+// keyActions are interactiveMode's commands. Station numbers aren't
+// among them; they can't be rebound.
+var keyActions = []keyAction{
+	{"stop", []string{"s", "stop"}}, // "stop shuffle" ends a shuffle instead
+	{"play", []string{"play"}},
+	{"pause", []string{"pause"}},
+	{"volume", []string{"v"}},
+	{"volume-up", []string{"+", "up", "\x1b[A"}}, // arrow keys, when input is line-buffered
+	{"volume-down", []string{"-", "down", "\x1b[B"}},
+	{"eq", []string{"eq"}},
+	{"list", []string{"l"}},
+	{"rate", []string{"rate"}},
+	{"random", []string{"r"}},
+	{"next", []string{"next"}},
+	{"prev", []string{"prev"}},
+	{"reveal", []string{"reveal"}},
+	{"recent", []string{"recent"}},
+	{"back", []string{"back"}},
+	{"shuffle", []string{"shuffle"}},
+	{"status", []string{"status"}},
+	{"check", []string{"check"}},
+	{"copy", []string{"copy"}},
+	{"open", []string{"open"}},
+	{"viz", []string{"viz"}},
+	{"deps", []string{"deps"}},
+	{"add", []string{"add"}},
+	{"edit", []string{"edit"}},
+	{"remove", []string{"remove"}},
+	{"reload", []string{"reload"}},
+	{"export", []string{"export"}},
+	{"alarm", []string{"alarm"}},
+	{"quit", []string{"q"}},
+	{"help", []string{"h"}},
+}
+
+// keyList is a keybinding's keys: one key as a string, or a list of them
+type keyList []string
+
+func (k *keyList) UnmarshalJSON(data []byte) error {
+	var key string
+	if err := json.Unmarshal(data, &key); err == nil {
+		*k = keyList{key}
+		return nil
+	}
+	var keys []string
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return fmt.Errorf("keys must be a string or a list of strings")
+	}
+	*k = keys
+	return nil
+}
+
+// keymap maps the keys typed at the prompt to the actions they run
+type keymap struct {
+	actions map[string]string   // action by key
+	keys    map[string][]string // keys by action; the first is shown in help
+}
+
+// newKeymap binds the default keys, with the config file's keybindings
+// (keys by action name) in place of an action's defaults. It fails on an
+// unknown action, a key that can't be typed as a command, or a key left
+// bound to two actions.
+func newKeymap(bindings map[string]keyList) (*keymap, error) {
+	km := &keymap{actions: map[string]string{}, keys: map[string][]string{}}
+	for _, a := range keyActions {
+		km.keys[a.name] = a.keys
+	}
+	for name, keys := range bindings {
+		if _, ok := km.keys[name]; !ok {
+			return nil, fmt.Errorf("keybindings: there's no %q command", name)
+		}
+		if len(keys) == 0 {
+			return nil, fmt.Errorf("keybindings: %s has no keys", name)
+		}
+		for _, key := range keys {
+			if err := checkKey(key); err != nil {
+				return nil, fmt.Errorf("keybindings: %s: %w", name, err)
+			}
+		}
+		km.keys[name] = keys
+	}
+	// In help order, so the error names the same pair every time
+	for _, a := range keyActions {
+		for _, key := range km.keys[a.name] {
+			if other, ok := km.actions[key]; ok && other != a.name {
+				return nil, fmt.Errorf("keybindings: %q is bound to both %s and %s", key, other, a.name)
+			}
+			km.actions[key] = a.name
+		}
+	}
+	return km, nil
+}
+
+// checkKey reports why key can't be bound, if it can't
+func checkKey(key string) error {
+	switch {
+	case key == "":
+		return fmt.Errorf("empty key")
+	case strings.ContainsFunc(key, func(r rune) bool { return r == ' ' || r == '\t' }):
+		// The prompt splits a command from its argument at the first space
+		return fmt.Errorf("key %q has a space in it (use %q for the space bar)", key, spaceKey)
+	}
+	if _, err := strconv.Atoi(key); err == nil {
+		return fmt.Errorf("key %q would hide station %s", key, key)
+	}
+	return nil
+}
+
+// action returns the action bound to key, or "" when there's none
+func (km *keymap) action(key string) string {
+	return km.actions[key]
+}
+
+// key returns the key shown for action in help
+func (km *keymap) key(action string) string {
+	return km.keys[action][0]
+}
+
+// commands returns the bound keys that are words to type, for suggesting
+// a fix for typos
+func (km *keymap) commands() []string {
+	var words []string
+	for _, a := range keyActions {
+		for _, key := range km.keys[a.name] {
+			if !strings.HasPrefix(key, "\x1b") {
+				words = append(words, key)
+			}
+		}
+	}
+	return words
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	lastfmAPIURL = "https://ws.audioscrobbler.com/2.0/"
+	// Tracks shorter than this aren't scrobbled, per Last.fm's rules
+	scrobbleMinPlay = 30 * time.Second
+	// track.scrobble accepts at most this many tracks per request
+	scrobbleBatchSize = 50
+	// Cap the retry queue so a long offline session can't grow without bound
+	scrobbleMaxPending = 500
+)
+
+// LastFMConfig holds the Last.fm API credentials from the config file.
+// The session key comes from Last.fm's auth.getSession flow.
+type LastFMConfig struct {
+	APIKey     string `json:"api_key"`
+	APISecret  string `json:"api_secret"`
+	SessionKey string `json:"session_key"`
+}
+
+// scrobbleTrack is a track waiting to be scrobbled
+type scrobbleTrack struct {
+	Artist    string
+	Title     string
+	StartedAt time.Time
+}
+
+// Scrobbler reports stream titles to Last.fm. Network work happens on
+// background goroutines so playback is never held up, and failed scrobbles
+// are queued and retried with the next submission.
+type Scrobbler struct {
+	cfg    LastFMConfig
+	client *http.Client
+
+	mu       sync.Mutex
+	current  *scrobbleTrack
+	pending  []scrobbleTrack
+	disabled bool
+	sending  bool
+}
+
+// NewScrobbler checks the credentials are present and returns a scrobbler
+func NewScrobbler(cfg LastFMConfig, client *http.Client) (*Scrobbler, error) {
+	if cfg.APIKey == "" || cfg.APISecret == "" || cfg.SessionKey == "" {
+		return nil, errors.New("lastfm config needs api_key, api_secret, and session_key")
+	}
+	return &Scrobbler{cfg: cfg, client: client}, nil
+}
+
+// TitleChanged records a new stream title: the previous track is queued
+// for scrobbling if it played long enough, and the new one is sent as
+// now playing. Titles that aren't "Artist - Title" end the current track
+// without starting a new one.
+func (s *Scrobbler) TitleChanged(title string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.disabled {
+		return
+	}
+	s.finishCurrentLocked()
+
+	artist, track, ok := splitArtistTitle(title)
+	if !ok {
+		s.current = nil
+		return
+	}
+	s.current = &scrobbleTrack{Artist: artist, Title: track, StartedAt: time.Now()}
+	go s.call("track.updateNowPlaying", map[string]string{"artist": artist, "track": track})
+	s.flushLocked()
+}
+
+// Close scrobbles the current track if it played long enough. It waits for
+// that submission so the last track isn't lost on quit.
+func (s *Scrobbler) Close() {
+	s.mu.Lock()
+	if s.disabled {
+		s.mu.Unlock()
+		return
+	}
+	s.finishCurrentLocked()
+	batch := s.takeBatchLocked()
+	s.mu.Unlock()
+	if len(batch) > 0 {
+		s.submit(batch)
+	}
+}
+
+func (s *Scrobbler) finishCurrentLocked() {
+	if s.current == nil {
+		return
+	}
+	if time.Since(s.current.StartedAt) >= scrobbleMinPlay {
+		s.pending = append(s.pending, *s.current)
+		if len(s.pending) > scrobbleMaxPending {
+			s.pending = s.pending[len(s.pending)-scrobbleMaxPending:]
+		}
+	}
+	s.current = nil
+}
+
+// flushLocked starts a background submission unless one is already running
+func (s *Scrobbler) flushLocked() {
+	if s.sending {
+		return
+	}
+	batch := s.takeBatchLocked()
+	if len(batch) == 0 {
+		return
+	}
+	s.sending = true
+	go func() {
+		s.submit(batch)
+		s.mu.Lock()
+		s.sending = false
+		s.mu.Unlock()
+	}()
+}
+
+func (s *Scrobbler) takeBatchLocked() []scrobbleTrack {
+	n := len(s.pending)
+	if n > scrobbleBatchSize {
+		n = scrobbleBatchSize
+	}
+	batch := append([]scrobbleTrack(nil), s.pending[:n]...)
+	s.pending = s.pending[n:]
+	return batch
+}
+
+// submit sends one track.scrobble batch, requeueing it on failure
+func (s *Scrobbler) submit(batch []scrobbleTrack) {
+	params := map[string]string{}
+	for i, t := range batch {
+		idx := fmt.Sprintf("[%d]", i)
+		params["artist"+idx] = t.Artist
+		params["track"+idx] = t.Title
+		params["timestamp"+idx] = strconv.FormatInt(t.StartedAt.Unix(), 10)
+	}
+	if err := s.call("track.scrobble", params); err != nil {
+		slog.Debug("scrobble failed; will retry", "tracks", len(batch), "err", err)
+		s.mu.Lock()
+		if !s.disabled {
+			s.pending = append(batch, s.pending...)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// lastfmError is an error response from the Last.fm API
+type lastfmError struct {
+	Code    int    `json:"error"`
+	Message string `json:"message"`
+}
+
+func (e *lastfmError) Error() string {
+	return fmt.Sprintf("last.fm error %d: %s", e.Code, e.Message)
+}
+
+// isAuthError reports whether retrying can't help: bad key, secret, or session
+func (e *lastfmError) isAuthError() bool {
+	switch e.Code {
+	case 4, 9, 10, 13, 14, 26:
+		return true
+	}
+	return false
+}
+
+// call makes a signed Last.fm API POST. Authentication failures disable
+// the scrobbler with a single warning; other errors are returned for retry.
+func (s *Scrobbler) call(method string, params map[string]string) error {
+	form := url.Values{}
+	for k, v := range params {
+		form.Set(k, v)
+	}
+	form.Set("method", method)
+	form.Set("api_key", s.cfg.APIKey)
+	form.Set("sk", s.cfg.SessionKey)
+	form.Set("api_sig", lastfmSignature(form, s.cfg.APISecret))
+	form.Set("format", "json")
+
+	resp, err := s.client.PostForm(lastfmAPIURL, form)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var apiErr lastfmError
+	if err := json.NewDecoder(resp.Body).Decode(&apiErr); err == nil && apiErr.Code != 0 {
+		if apiErr.isAuthError() {
+			s.mu.Lock()
+			if !s.disabled {
+				s.disabled = true
+				s.pending = nil
+				slog.Warn("Last.fm scrobbling disabled", "err", &apiErr)
+			}
+			s.mu.Unlock()
+		}
+		return &apiErr
+	}
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("last.fm: %s", resp.Status)
+	}
+	return nil
+}
+
+// lastfmSignature computes api_sig: the md5 of every parameter name and
+// value concatenated in name order, followed by the shared secret
+func lastfmSignature(form url.Values, secret string) string {
+	keys := make([]string, 0, len(form))
+	for k := range form {
+		if k == "format" || k == "callback" {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteString(form.Get(k))
+	}
+	b.WriteString(secret)
+	sum := md5.Sum([]byte(b.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// splitArtistTitle parses the common "Artist - Title" stream title format
+func splitArtistTitle(title string) (string, string, bool) {
+	artist, track, ok := strings.Cut(title, " - ")
+	artist = strings.TrimSpace(artist)
+	track = strings.TrimSpace(track)
+	if !ok || artist == "" || track == "" {
+		return "", "", false
+	}
+	return artist, track, true
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// parseLogLevel reads a -log-level value
+func parseLogLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q (use debug, info, warn, or error)", s)
+}
+
+// setupLogging makes the default slog logger write diagnostics at level
+// and above to path, appending, or to stderr when path is empty. Lines on
+// stderr leave out the time, which the user can see anyway. The returned
+// closer closes the log file.
+func setupLogging(level slog.Level, path string) (io.Closer, error) {
+	opts := &slog.HandlerOptions{Level: level}
+	var w io.WriteCloser = nopCloser{os.Stderr}
+	if path != "" {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		w = f
+	} else {
+		opts.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		}
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(w, opts)))
+	return w, nil
+}
+
+// nopCloser keeps stderr open when the log is closed
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
@@ -1,28 +1,30 @@
 package main
 
 import (
-	"bufio"
+	"cmp"
 	"context"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
 	"os"
 	"os/exec"
 	"os/signal"
-	"regexp"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
-)
 
-type Station struct {
-	Name        string
-	URL         string
-	Description string
-}
+	"github.com/hhaidrr/cli-radio-player/pkg/radio"
+)
 
-var defaultStations = []Station{
+var defaultStations = []radio.Station{
 	{Name: "Lofi Hip Hop Radio - beats to relax/study to", URL: "https://www.youtube.com/watch?v=jfKfPfyJRdk", Description: "The most popular lofi radio station"},
 	{Name: "ChilledCow - Lofi Hip Hop Radio", URL: "https://www.youtube.com/watch?v=5qap5aO4i9A", Description: "Classic lofi beats for studying"},
 	{Name: "Lofi Girl - 24/7 lofi hip hop radio", URL: "https://www.youtube.com/watch?v=DWcJFNfaw9c", Description: "24/7 lofi hip hop radio stream"},
@@ -30,396 +32,2155 @@ var defaultStations = []Station{
 	{Name: "Lofi Hip Hop Radio - Beats to sleep/chill to", URL: "https://www.youtube.com/watch?v=rUxyKA_-grg", Description: "Relaxing lofi beats for sleep"},
 }
 
+// Player is the radio engine plus what the CLI keeps around it: the
+// station list position, the global volume, and the displays
 type Player struct {
-	mu             sync.Mutex
-	cmd            *exec.Cmd
+	*radio.Player
 	currentStation int
-	volumePercent  int
-	isStopped      bool
-	visualization  bool
-	analyzer       *StreamAnalyzer
+	baseVolume     int    // the global volume, used by stations without their own
+	stationVolume  bool   // the volume comes from the station's override
+	statsFormat    string // statsFull, statsCompact, or statsJSON
+	volumeStep     int
+	alarmRamp      time.Duration
+	sessionStart   time.Time
+	statsInterval  time.Duration
+	connectTimeout time.Duration // -connect-timeout, for messages
+	silenceAfter   time.Duration // -silence, for messages
+	skipSilent     bool          // -skip-silent: move on from silent stations
+	skipDead       bool          // move on from stations that time out
+	skipFrom       int           // first station of a run of dead ones
+	skipTo         int           // station last skipped to; -1 when none
+	detach         bool          // -detach: quitting leaves the stream playing
+	batch          bool          // -once-per-line: commands come from a script, without prompts
+
+	schedule []scheduleRule // -schedule's rules, by time; nil when off
+
+	keys *keymap // interactive commands by key
+
+	session    sessionLog // what played, for the summary on quit and export
+	configPath string     // the config file read at startup, for export; "" when none
+	flags      []string   // the command line's flags, for export
+
+	alerts      alertWatch // quality alerts announced so far
+	alertBell   bool       // -alert-bell: ring the terminal bell on new alerts
+	alertNotify bool       // -alert-notify: show new alerts as desktop notifications
+
+	shuffleFavs time.Duration // favorites shuffle interval, kept in the state; 0 when off
+
+	visualization atomic.Bool // toggled by viz; status reads it from other goroutines
+	blind         bool        // -blind: station names are hidden until revealed
+	revealed      atomic.Bool // reveal has shown the current station's name
+
+	autoCurve      []volumePoint // -auto-volume's curve; nil when off
+	autoVolume     int           // the curve's volume for the current hour
+	volumeOverride bool          // the user has set the volume since the curve's last point
+
+	// stationMu is held while switching stations; it guards currentStation
+	// and, in interactive and standard mode, the station list
+	stationMu sync.Mutex
+
+	mu         sync.Mutex       // guards statusLine, title, loading, the art, ratings, shuffleFavs, and the auto volume
+	presence   *DiscordPresence // shows the station on Discord; nil when off
+	statusLine *statusLine      // bottom-row volume and state; nil when off
+	title      *marquee         // scrolls the track title in the status line
+	loading    bool             // a loading spinner owns the current line
+
+	artProtocol string      // -art: imageKitty or imageITerm; "" when off
+	artStation  string      // URL of the station whose artwork is loaded or loading
+	art         *stationArt // nil until loaded
+	artUploaded bool        // art.upload has been sent
+
+	ratings map[string]int // stars given with rate, by station URL
 }
 
-func NewPlayer() *Player {
-	return &Player{
+func newPlayer(opts ...radio.Option) *Player {
+	p := &Player{
+		Player:         radio.NewPlayer(opts...),
 		currentStation: 0,
-		volumePercent:  70,
-		visualization:  false,
-		analyzer:       NewStreamAnalyzer(),
+		baseVolume:     70,
+		volumeStep:     5,
+		statsFormat:    statsFull,
+		statsInterval:  radio.DefaultStatsInterval,
+		alarmRamp:      DefaultAlarmRamp,
+		sessionStart:   time.Now(),
+		skipTo:         -1,
+		title:          newMarquee(defaultMarqueeWidth, defaultMarqueeSpeed),
 	}
+	// The defaults alone always bind
+	p.keys, _ = newKeymap(nil)
+	p.OnChange(p.stateChanged)
+	return p
 }
 
-func (p *Player) ffplayArgs(url string) []string {
-	// ffplay volume uses dB via -af volume=...; map 0-100% to -20..+0 dB approx
-	volDb := float64(p.volumePercent)/100*0 - 20*(1-float64(p.volumePercent)/100)
-	volFilter := fmt.Sprintf("volume=%fdB", volDb)
-	args := []string{
-		"-nodisp",
-		"-autoexit",
-		"-loglevel", "warning", // Keep warning level for audio processing
-		"-hide_banner", // Hide ffplay banner
-		"-af", volFilter,
-		url,
-	}
-	if p.visualization {
-		// Use showwavespic as a lightweight visualization in a separate window
-		// However -nodisp disables it; keep nodisp for headless. Toggle simply prints a note.
+// stateChanged keeps the status line and Discord activity in step with
+// the engine
+func (p *Player) stateChanged(st radio.PlayerStatus) {
+	p.session.played(st.Name, st.Playing, time.Now())
+	if p.presence != nil {
+		if st.Playing {
+			p.presence.Playing(p.displayName(st.Name), st.Since)
+		} else {
+			p.presence.Stopped()
+		}
 	}
-	return args
+	p.refreshStatusLine(st)
 }
 
-func (p *Player) Start(url string) error {
+// refreshStatusLine redraws the status line from the engine's state and
+// the playing stream's track title
+func (p *Player) refreshStatusLine(st radio.PlayerStatus) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	if p.cmd != nil && p.cmd.Process != nil {
-		return errors.New("player already running")
-	}
-	resolved, err := resolvePlayableURL(url)
-	if err != nil {
-		return err
-	}
-
-	// Start stream analysis
-	if err := p.analyzer.StartAnalysis(resolved); err != nil {
-		// Don't fail the entire start if analysis fails
-		fmt.Printf("Warning: Could not start stream analysis: %v\n", err)
+	if p.statusLine == nil {
+		return
 	}
-
-	args := p.ffplayArgs(resolved)
-	p.cmd = exec.Command("ffplay", args...)
-	p.cmd.Stdout = os.Stdout
-	p.cmd.Stderr = os.Stderr
-	if err := p.cmd.Start(); err != nil {
-		p.cmd = nil
-		return err
+	var title string
+	if st.Playing && !p.hidden() {
+		now := time.Now()
+		text := p.Analyzer().GetStats().Title
+		if text == "" {
+			text = st.Track
+		}
+		p.title.Set(text, now)
+		title = p.title.Render(now)
 	}
-	p.isStopped = false
-	go func(cmd *exec.Cmd) {
-		_ = cmd.Wait()
-		p.mu.Lock()
-		defer p.mu.Unlock()
-		p.cmd = nil
-	}(p.cmd)
-	return nil
+	p.statusLine.Set(statusText(st.Volume, st.Playing, p.displayName(st.Name), title))
 }
 
-func (p *Player) Stop() error {
+// resized signals when the terminal changes size; it never fires without
+// a status line
+func (p *Player) resized() <-chan struct{} {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	if p.cmd == nil || p.cmd.Process == nil {
-		p.isStopped = true
-		p.analyzer.StopAnalysis()
+	if p.statusLine == nil {
 		return nil
 	}
-	p.isStopped = true
+	return p.statusLine.Resized()
+}
 
-	// Stop stream analysis
-	p.analyzer.StopAnalysis()
+// hasStatusLine reports whether volume and state changes show on the
+// status line rather than as printed lines
+func (p *Player) hasStatusLine() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.statusLine != nil
+}
 
-	// Send SIGTERM to stop the process
-	if err := p.cmd.Process.Signal(syscall.SIGTERM); err != nil {
-		return err
+// redrawStatusLine draws the status line again, e.g. after the screen was
+// cleared
+func (p *Player) redrawStatusLine() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.statusLine != nil {
+		p.statusLine.Redraw()
 	}
+}
 
-	// Wait for the process to actually exit
-	done := make(chan error, 1)
-	go func() {
-		done <- p.cmd.Wait()
-	}()
-
-	select {
-	case <-done:
-		// Process exited
-		p.cmd = nil
-		return nil
-	case <-time.After(2 * time.Second):
-		// Force kill if it doesn't exit within 2 seconds
-		p.cmd.Process.Kill()
-		p.cmd = nil
-		return nil
+// toggleVisualization flips the viz setting and returns the new one
+func (p *Player) toggleVisualization() bool {
+	for {
+		on := p.visualization.Load()
+		if p.visualization.CompareAndSwap(on, !on) {
+			return !on
+		}
 	}
 }
 
-func (p *Player) Restart(url string) error {
-	_ = p.Stop()
-	return p.Start(url)
+// reportStartError explains a failed Start. When a required binary has
+// disappeared since startup it re-runs the dependency check so the user
+// gets the same install guidance as at launch.
+func reportStartError(err error, backend string) {
+	fmt.Printf("Failed to start stream: %v\n", err)
+	if hint := radio.ResolveHint(err); hint != "" {
+		fmt.Println("Try:", hint)
+	}
+	if !errors.Is(err, exec.ErrNotFound) {
+		return
+	}
+	if depErr := radio.CheckDependencies(backend); depErr != nil {
+		fmt.Println("Error:", depErr)
+	}
+	fmt.Println("Type 'deps' to re-check dependencies, then pick a station to retry.")
 }
 
-// resolvePlayableURL returns a direct media URL that ffplay can consume.
-// For YouTube links, it uses yt-dlp -g to get the direct audio URL (same as your working command).
-func resolvePlayableURL(originalURL string) (string, error) {
-	if !isYouTubeURL(originalURL) {
-		return originalURL, nil
+// setUserVolume is SetVolume for a change the user asked for. On a station
+// without its own volume it also becomes the global volume.
+func (p *Player) setUserVolume(percent int) bool {
+	live := p.SetVolume(percent)
+	if !p.stationVolume {
+		p.baseVolume = p.Volume()
+		p.holdAutoVolume()
 	}
+	return live
+}
 
-	// Use yt-dlp -g to get the direct audio URL (same as your working command)
-	cmd := exec.Command(ytdlpBinary, "-g", "-f", "bestaudio/best", originalURL)
-	var stdout, stderr strings.Builder
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("yt-dlp failed: %v, stderr: %s", err, stderr.String())
+// applyStation takes on a station's settings before it's started: its HTTP
+// headers and credentials, and its own volume, or the global one when it
+// has none. It doesn't restart the stream.
+func (p *Player) applyStation(st radio.Station) {
+	p.revealed.Store(false)
+	p.SetStation(st)
+	p.loadArt(st)
+	if st.Volume != nil {
+		p.stationVolume = true
+		p.SetVolume(*st.Volume)
+		return
 	}
+	p.stationVolume = false
+	p.SetVolume(p.globalVolume())
+}
 
-	output := strings.TrimSpace(stdout.String())
-	if output == "" {
-		return "", fmt.Errorf("yt-dlp did not return a media URL, stderr: %s", stderr.String())
+// changeVolume sets the volume and makes it audible: live on backends that
+// support it, otherwise by restarting the stream if it's playing
+func (p *Player) changeVolume(percent int, url string) {
+	before := p.Volume()
+	live := p.setUserVolume(percent)
+	if !p.hasStatusLine() {
+		fmt.Printf("Volume set to %s\n", volumeText(p.Volume()))
 	}
+	if before <= 100 && p.Volume() > 100 {
+		warnBoost()
+	}
+	p.persistState()
+	if !live && p.Playing() {
+		if err := p.Restart(url); err != nil {
+			reportStartError(err, p.Backend())
+		}
+	}
+}
 
-	// Return the first line (the audio URL)
-	lines := strings.Split(output, "\n")
-	return strings.TrimSpace(lines[0]), nil
+// volumeText shows a volume percentage, with the gain when it's boosted
+// past 100%
+func volumeText(percent int) string {
+	if percent <= 100 {
+		return fmt.Sprintf("%d%%", percent)
+	}
+	return fmt.Sprintf("%d%% (boost, +%.1f dB)", percent, radio.VolumeBoostDB(percent))
 }
 
-var ytRegexp = regexp.MustCompile(`(?i)^(https?://)?(www\.)?(youtube\.com|youtu\.be)/`)
+// warnBoost is shown when the volume goes past 100%
+func warnBoost() {
+	uiPrintln("⚠️  Boosting past 100% amplifies the stream; loud passages may clip and distort")
+}
 
-func isYouTubeURL(u string) bool {
-	return ytRegexp.MatchString(u)
+// setDataCap warns once the session has downloaded capBytes, and stops
+// playback too when stop is set
+func (p *Player) setDataCap(capBytes int64, stop bool) {
+	p.SetDataCap(capBytes, func(used int64) {
+		uiPrintf("\n⚠️  Data cap reached: %s used this session\n", radio.FormatBytes(used))
+		if stop {
+			_ = p.Stop()
+			fmt.Println("Playback stopped. Pick a station to keep listening.")
+		}
+	})
 }
 
-// checkDependencies verifies that required external tools are available
-func checkDependencies() error {
-	// Check for ffplay
-	if _, err := exec.LookPath("ffplay"); err != nil {
-		return fmt.Errorf("ffplay not found. Please install FFmpeg: sudo apt install ffmpeg")
+// reportAdaptive tells the user about a -adaptive quality change
+func (p *Player) reportAdaptive(sw radio.AdaptiveSwitch) {
+	switch {
+	case sw.Err != nil:
+		reportStartError(sw.Err, p.Backend())
+	case sw.Down:
+		uiPrintf("\n📉 Adaptive: network %s for %s, switching down to %s quality\n", sw.Network, formatElapsed(sw.For), sw.Quality)
+	default:
+		uiPrintf("\n📈 Adaptive: network %s for %s, switching back up to %s quality\n", sw.Network, formatElapsed(sw.For), sw.Quality)
 	}
+}
 
-	// Check for yt-dlp (needed for YouTube URLs) - use system yt-dlp
-	if _, err := exec.LookPath("yt-dlp"); err != nil {
-		return fmt.Errorf("yt-dlp not found. Please install yt-dlp: sudo curl -L https://github.com/yt-dlp/yt-dlp/releases/latest/download/yt-dlp -o /usr/local/bin/yt-dlp && sudo chmod a+rx /usr/local/bin/yt-dlp")
+// deadReason says why the player gave up on a station, e.g. "Jazz is
+// unreachable: no response after 15s"
+func (p *Player) deadReason(station radio.Station) string {
+	name := p.displayName(station.Name)
+	if p.Analyzer().GetStats().Silent {
+		return fmt.Sprintf("%s is silent: no audio for %s", name, p.silenceAfter)
 	}
+	return fmt.Sprintf("%s is unreachable: no response after %s", name, p.connectTimeout)
+}
 
-	// Use system yt-dlp
-	ytdlpBinary = "yt-dlp"
-
-	return nil
+// unreachable reports a station the player gave up on after
+// -connect-timeout, or -silence with -skip-silent. With -skip-dead, or
+// -skip-silent for a silent one, it moves on with play, until every
+// station has failed in a row; it reports whether it did.
+func (p *Player) unreachable(stations []radio.Station, play func(idx int)) bool {
+	uiPrintf("\n⚠️  %s\n", p.deadReason(stations[p.currentStation]))
+	return p.skipDeadStation(stations, play)
 }
 
-var ytdlpBinary string
+// skipDeadStation moves on from the current station with play when it's
+// to be skipped as dead, like unreachable without the report
+func (p *Player) skipDeadStation(stations []radio.Station, play func(idx int)) bool {
+	skip := p.skipDead || p.skipSilent && p.Analyzer().GetStats().Silent
+	if !skip || len(stations) < 2 {
+		return false
+	}
+	if p.skipTo != p.currentStation {
+		// A new run of dead stations
+		p.skipFrom = p.currentStation
+	}
+	next := (p.currentStation + 1) % len(stations)
+	if next == p.skipFrom {
+		p.skipTo = -1
+		fmt.Println("None of the stations answered.")
+		return false
+	}
+	p.skipTo = next
+	play(next)
+	return true
+}
 
-func (p *Player) SetVolume(percent int) {
-	if percent < 0 {
-		percent = 0
+// persistState saves the current player settings for the next run
+func (p *Player) persistState() {
+	st := State{
+		Station: p.currentStation,
+		Volume:  p.baseVolume,
+		EQ:      p.EQ(),
+		Quality: p.Quality(),
+		Device:  p.Device(),
+		Mono:    p.Mono(),
+		Ratings: p.savedRatings(),
+	}
+	p.mu.Lock()
+	if p.shuffleFavs > 0 {
+		st.ShuffleFavs = p.shuffleFavs.String()
 	}
-	if percent > 100 {
-		percent = 100
+	p.mu.Unlock()
+	if err := saveState(st); err != nil {
+		slog.Warn("could not save state", "err", err)
 	}
-	p.volumePercent = percent
 }
 
 func (p *Player) showQualityAlerts() {
-	if p.analyzer == nil {
+	if p.Analyzer() == nil {
 		return
 	}
 
-	alerts := p.analyzer.GetQualityAlerts()
+	alerts := p.Analyzer().GetQualityAlerts()
 
 	if len(alerts) > 0 {
-		fmt.Println("\n⚠️  Quality Alerts:")
+		uiPrintln("\n⚠️  Quality Alerts:")
 		for _, alert := range alerts {
-			fmt.Printf("   • %s\n", alert)
+			uiPrintf("   • %s\n", alert)
 		}
 	}
 }
 
+// printPrompt prints the radio> prompt, colored by the playing stream's
+// network quality
+func (p *Player) printPrompt() {
+	if p.batch {
+		return
+	}
+	var quality string
+	if p.Playing() {
+		quality = p.Analyzer().GetStats().NetworkQuality
+	}
+	fmt.Print(promptText(quality))
+}
+
 func (p *Player) displayStatsLoop(ctx context.Context) {
-	ticker := time.NewTicker(500 * time.Millisecond)
+	ticker := time.NewTicker(p.statsInterval / 2)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
+		case <-p.resized():
+			// Refit the stats block now rather than on the next tick
+			if p.statsFormat != statsFull {
+				continue
+			}
 		case <-ticker.C:
-			if !p.isStopped {
-				// Clear screen and show stats
+		}
+		// Scroll the track title along
+		p.refreshStatusLine(p.Status())
+		p.mu.Lock()
+		loading := p.loading
+		p.mu.Unlock()
+		if p.Playing() && !loading {
+			alerts := p.Analyzer().GetQualityAlerts()
+			p.session.sample(p.Analyzer().GetStats(), alerts)
+			p.announceAlerts(alerts)
+		}
+		switch p.statsFormat {
+		case statsJSON:
+			// One JSON object per line for status bars and scripts
+			if data, err := p.Analyzer().GetStatsJSON(); err == nil {
+				fmt.Println(string(data))
+			}
+			continue
+		case statsCompact:
+			uiPrintln(p.Analyzer().FormatStatsCompact())
+			continue
+		}
+		if p.Playing() && !loading {
+			// Clear screen and show stats
+			if plainOutput {
+				fmt.Println()
+			} else {
 				fmt.Print("\033[2J\033[H") // Clear screen and move cursor to top
-				fmt.Print(p.analyzer.FormatStats())
-
-				// Show quality alerts
-				alerts := p.analyzer.GetQualityAlerts()
-				if len(alerts) > 0 {
-					fmt.Println("\n⚠️  Quality Alerts:")
-					for _, alert := range alerts {
-						fmt.Printf("   • %s\n", alert)
-					}
-				}
+			}
+			// Cut lines to the terminal's width so they don't wrap
+			cols := terminalCols()
+			p.drawArt(cols)
+			uiPrintf("%s", fitWidth(p.Analyzer().FormatStats(), cols))
+			uiPrintf("%s", fitWidth(fmt.Sprintf("\n⏱️  Playing for %s / Session %s\n", formatElapsed(p.StationElapsed()), formatElapsed(time.Since(p.sessionStart))), cols))
+			// Clearing the screen wiped it
+			p.redrawStatusLine()
 
-				fmt.Print("radio> ")
+			// Show quality alerts
+			alerts := p.Analyzer().GetQualityAlerts()
+			if len(alerts) > 0 {
+				uiPrintln("\n⚠️  Quality Alerts:")
+				for _, alert := range alerts {
+					uiPrintf("%s", fitWidth(fmt.Sprintf("   • %s\n", alert), cols))
+				}
 			}
+
+			p.printPrompt()
 		}
 	}
 }
 
+// formatElapsed formats a duration to the second, e.g. 45s, 12m05s, 1h03m
+func formatElapsed(d time.Duration) string {
+	d = d.Truncate(time.Second)
+	h, m, sec := int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60
+	switch {
+	case h > 0:
+		return fmt.Sprintf("%dh%02dm", h, m)
+	case m > 0:
+		return fmt.Sprintf("%dm%02ds", m, sec)
+	default:
+		return fmt.Sprintf("%ds", sec)
+	}
+}
+
+// defaultSilence is how long a stream must be silent for -skip-silent to
+// give up on it when -silence isn't given
+const defaultSilence = 30 * time.Second
+
+// maxPrebuffer caps -prebuffer, in seconds; more than this is a long
+// silence at every start for little extra protection
+const maxPrebuffer = 60
+
+// Stats display formats for -stats-format
+const (
+	statsFull    = "full"    // multi-line block, redrawn in place
+	statsCompact = "compact" // one short line per update, for status bars
+	statsJSON    = "json"    // one JSON object per line
+)
+
 func printHeader(volume int, nowPlaying string) {
-	fmt.Printf("\n\U0001F50A Volume set to %d%%\n", volume)
-	fmt.Printf("\U0001F3B5 Now Playing: %s\n", nowPlaying)
-	fmt.Println("\u23F3 Loading stream...")
+	uiPrintf("\n\U0001F50A Volume set to %s\n", volumeText(volume))
+	if volume > 100 {
+		warnBoost()
+	}
+	uiPrintf("\U0001F3B5 Now Playing: %s\n", nowPlaying)
+}
+
+func printHelp(keys *keymap, stationCount, volumeStep int) {
+	k := keys.key
+	uiPrintln()
+	uiPrintln("\U0001F4AA Controls:")
+	uiPrintf("  [%s] Stop playback (%s shuffle ends a shuffle and keeps playing)\n", k("stop"), k("stop"))
+	uiPrintf("  [%s] Start the current station again after stopping\n", k("play"))
+	uiPrintf("  [%s] Pause, or resume after pausing\n", k("pause"))
+	uiPrintf("  [%s] Change volume\n", k("volume"))
+	uiPrintf("  [%s/%s] Volume up/down by %d%%\n", k("volume-up"), k("volume-down"), volumeStep)
+	uiPrintf("  [%s] Change equalizer preset\n", k("eq"))
+	uiPrintf("  [%s] List all stations (%s rating puts the highest rated first)\n", k("list"), k("list"))
+	uiPrintf("  [%s N] Rate the current station 1-%d stars (%s 0 to clear)\n", k("rate"), maxRating, k("rate"))
+	uiPrintf("  [%s] Random station\n", k("random"))
+	uiPrintf("  [%s/%s] Next/previous station in the list\n", k("next"), k("prev"))
+	uiPrintf("  [%s] Show the station's name with -blind\n", k("reveal"))
+	uiPrintf("  [%s] List recently played stations\n", k("recent"))
+	uiPrintf("  [%s] Go back to the previous station\n", k("back"))
+	uiPrintf("  [%[1]s N] Switch to a random station every N minutes (%[1]s N rated favors higher-rated ones; %[1]s N favs keeps to 4 and 5 stars; %[1]s off to stop)\n", k("shuffle"))
+	uiPrintf("  [%s] Show current station, volume, and player setup\n", k("status"))
+	uiPrintf("  [%s] Check which stations are reachable\n", k("check"))
+	uiPrintf("  [%s] Copy the station URL to the clipboard (%s resolved for the media URL)\n", k("copy"), k("copy"))
+	uiPrintf("  [%s] Open the station's page, such as its YouTube video, in the browser\n", k("open"))
+	uiPrintf("  [%s] Toggle visualization\n", k("viz"))
+	uiPrintf("  [%s] Re-check ffplay/yt-dlp\n", k("deps"))
+	uiPrintf("  [%s] Add a station to the config file\n", k("add"))
+	uiPrintf("  [%s N] Change station N's name, URL, or description\n", k("edit"))
+	uiPrintf("  [%s N] Remove station N from the config file\n", k("remove"))
+	uiPrintf("  [%s] Reload stations from the config file\n", k("reload"))
+	uiPrintf("  [%s PATH] Write a report of the session so far to a Markdown file, e.g. for a bug report\n", k("export"))
+	uiPrintf("  [%s HH:MM] Start playing at a time (%s off to cancel)\n", k("alarm"), k("alarm"))
+	uiPrintf("  [%s] Quit\n", k("quit"))
+	uiPrintf("  [%s] Show this help\n", k("help"))
+	uiPrintf("  [1-%d] Switch station\n", stationCount)
+	uiPrintln()
+	uiPrintln("📊 Stream quality stats are displayed automatically")
+	uiPrintln()
 }
 
-func printHelp() {
-	fmt.Println()
-	fmt.Println("\U0001F4AA Controls:")
-	fmt.Println("  [s] Stop playback")
-	fmt.Println("  [v] Change volume")
-	fmt.Println("  [l] List all stations")
-	fmt.Println("  [viz] Toggle visualization")
-	fmt.Println("  [q] Quit")
-	fmt.Println("  [h] Show this help")
-	fmt.Println("  [1-5] Switch station")
-	fmt.Println()
-	fmt.Println("📊 Stream quality stats are displayed automatically")
-	fmt.Println()
+func printStatus(w io.Writer, p *Player, station radio.Station) {
+	st := p.Status()
+	state := "stopped"
+	switch {
+	case st.Playing:
+		state = "playing"
+	case st.Paused:
+		state = fmt.Sprintf("paused (%s to resume)", p.keys.key("pause"))
+	case st.Ready:
+		state = fmt.Sprintf("ready (resolved; %s to start)", p.keys.key("play"))
+	}
+	onOff := func(b bool) string {
+		if b {
+			return "on"
+		}
+		return "off"
+	}
+	eq := st.EQ
+	if eq == "" {
+		eq = "flat"
+	}
+	resolved := radio.RedactURL(st.ResolvedURL)
+	if resolved == "" {
+		resolved = "(not resolved yet)"
+	}
+	name, url := fmt.Sprintf("[%d] %s", p.currentStation+1, station.Name), radio.RedactURL(station.URL)
+	if p.hidden() {
+		// Hide everything that would give the station away
+		name, url, resolved, st.Track = hiddenName+fmt.Sprintf(" (type %s to show it)", p.keys.key("reveal")), hiddenName, hiddenName, ""
+	}
+	fmt.Fprintf(w, "Station:   %s\n", name)
+	fmt.Fprintf(w, "URL:       %s\n", url)
+	if redirects := redirectText(p.Analyzer().GetStats()); redirects != "" && !p.hidden() {
+		fmt.Fprintf(w, "Redirects: %s\n", redirects)
+	}
+	if st.Mirrors > 1 && !p.hidden() {
+		fmt.Fprintf(w, "Mirror:    %s\n", mirrorText(station, st))
+	}
+	if st.Track != "" {
+		fmt.Fprintf(w, "Track:     %s\n", st.Track)
+	}
+	if r := p.rating(station.URL); r > 0 && !p.hidden() {
+		fmt.Fprintf(w, "Rating:    %s\n", stars(r))
+	}
+	fmt.Fprintf(w, "State:     %s\n", state)
+	fmt.Fprintf(w, "Volume:    %s\n", volumeText(st.Volume))
+	fmt.Fprintf(w, "EQ:        %s\n", eq)
+	fmt.Fprintf(w, "Channels:  %s\n", channelsText(p.Analyzer().GetStats().ChannelLayout, st.Mono))
+	fmt.Fprintf(w, "Quality:   %s\n", st.Quality)
+	fmt.Fprintf(w, "Viz:       %s\n", onOff(p.visualization.Load()))
+	fmt.Fprintln(w, "Stats:     on")
+	fmt.Fprintf(w, "Backend:   %s\n", st.Backend)
+	device := st.Device
+	if device == "" {
+		device = "(system default)"
+	}
+	fmt.Fprintf(w, "Device:    %s\n", device)
+	fmt.Fprintf(w, "Media URL: %s\n", resolved)
 }
 
-func listStations(stations []Station) {
-	fmt.Println("Available Stations:")
-	for i, s := range stations {
-		fmt.Printf("  [%d] %s\n", i+1, s.Name)
-		fmt.Printf("      %s\n", s.Description)
+// mirrorText describes which of the station's stream URLs is in use for
+// status, e.g. "2 of 3 (https://backup.example.com/live)"
+func mirrorText(station radio.Station, st radio.PlayerStatus) string {
+	text := fmt.Sprintf("%d of %d", st.Mirror+1, st.Mirrors)
+	urls := station.StreamURLs()
+	switch {
+	case st.Mirror == 0:
+		return text + " (primary)"
+	case st.Mirror < len(urls):
+		return text + " (" + radio.RedactURL(urls[st.Mirror]) + ")"
 	}
+	return text
 }
 
-func interactiveMode(ctx context.Context, p *Player, stations []Station, startIdx int) {
+// redirectText describes the stream's redirects for status, or "" when
+// it isn't redirected
+func redirectText(stats radio.StreamStats) string {
+	if stats.RedirectError != "" {
+		return "not followed (" + stats.RedirectError + ")"
+	}
+	if stats.Redirects == 0 {
+		return ""
+	}
+	text := fmt.Sprintf("%d, ending at %s", stats.Redirects, stats.FinalHost())
+	if stats.Downgraded {
+		text += " (warning: downgraded from HTTPS to HTTP)"
+	}
+	return text
+}
+
+// channelsText describes the channels heard: the stream's layout, and the
+// downmix when -mono is on
+func channelsText(layout string, mono bool) string {
+	switch {
+	case mono && layout != "" && layout != "mono":
+		return layout + ", downmixed to mono"
+	case mono:
+		return "mono"
+	case layout == "":
+		return "unknown"
+	}
+	return layout
+}
+
+// randomStation picks a station index other than current (rand/v2 is
+// seeded randomly at startup)
+func randomStation(n, current int) int {
+	if n < 2 {
+		return current
+	}
+	i := rand.IntN(n - 1)
+	if i >= current {
+		i++
+	}
+	return i
+}
+
+// listStations prints the stations with their ratings, in list order or
+// highest rated first
+func listStations(stations []radio.Station, ratings map[string]int, sortByRating bool) {
+	order := make([]int, len(stations))
+	for i := range order {
+		order[i] = i
+	}
+	if sortByRating {
+		sortByRatingDesc(order, stations, ratings)
+	}
+	uiPrintln("Available Stations:")
+	for _, i := range order {
+		s := stations[i]
+		rated := ""
+		if r := stars(ratings[s.URL]); r != "" {
+			rated = "  " + r
+		}
+		uiPrintf("  [%d] %s%s\n", i+1, s.Name, rated)
+		if s.Description != "" {
+			uiPrintf("      %s\n", s.Description)
+		}
+	}
+}
+
+func interactiveMode(ctx context.Context, p *Player, stations []radio.Station, startIdx int, configPath, historyFile, recentFile string, paused bool) {
 	if startIdx < 0 || startIdx >= len(stations) {
 		startIdx = 0
 	}
+	// The command loop holds stationMu except while it waits for input,
+	// and the shuffle, schedule, alarm, and dead-station goroutines while
+	// they switch, so stations, now, and currentStation change one at a
+	// time
+	p.stationMu.Lock()
+	defer p.stationMu.Unlock()
 	p.currentStation = startIdx
+	// A script's output has no live display to make room for
+	if sl := newStatusLine(); sl != nil && !p.batch {
+		p.mu.Lock()
+		p.statusLine = sl
+		p.mu.Unlock()
+		defer func() {
+			p.mu.Lock()
+			p.statusLine = nil
+			p.mu.Unlock()
+			sl.Close()
+		}()
+	}
+	recent := &recentList{}
+	if recentFile != "" {
+		var err error
+		if recent, err = loadRecent(recentFile); err != nil {
+			slog.Warn("could not load recently played stations", "err", err)
+		}
+	}
 	now := stations[p.currentStation]
-	printHeader(p.volumePercent, now.Name)
-	_ = p.Start(now.URL)
-	printHelp()
+	// switchLocked plays station idx; callers hold stationMu
+	switchLocked := func(idx int) {
+		p.currentStation = idx
+		now = stations[p.currentStation]
+		recent.Visit(now, time.Now())
+		p.persistState()
+		p.applyStation(now)
+		fmt.Println("Switching to:", p.displayName(now.Name))
+		if err := p.startWithSpinner(func() error { return p.Restart(now.URL) }); err != nil {
+			reportStartError(err, p.Backend())
+		} else {
+			uiPrintln("✓ Now playing:", p.displayName(now.Name))
+		}
+	}
+	p.OnUnreachable(func(string) {
+		p.stationMu.Lock()
+		p.unreachable(stations, switchLocked)
+		p.stationMu.Unlock()
+		p.printPrompt()
+	})
+	p.applyStation(now)
+	recent.Visit(now, time.Now())
+	printHeader(p.Volume(), p.displayName(now.Name))
+	if paused {
+		// Resolve and connect now; sound waits for "play"
+		if err := p.startWithSpinner(func() error { return p.Prepare(now.URL) }); err != nil {
+			reportStartError(err, p.Backend())
+		} else {
+			uiPrintln("✓ Ready:", p.displayName(now.Name), fmt.Sprintf("(type %s to start)", p.keys.key("play")))
+		}
+	} else if err := p.startWithSpinner(func() error { return p.Start(now.URL) }); err != nil {
+		reportStartError(err, p.Backend())
+	}
+	if !p.batch {
+		printHelp(p.keys, len(stations), p.volumeStep)
+	}
 
 	// Start real-time stats display immediately
 	statsCtx, statsCancel := context.WithCancel(ctx)
 	defer statsCancel()
-	go p.displayStatsLoop(statsCtx)
+	if !p.batch {
+		go p.displayStatsLoop(statsCtx)
+	}
+
+	if p.schedule != nil {
+		go p.runSchedule(statsCtx, func(idx int) {
+			p.stationMu.Lock()
+			// Like shuffle, leave a stopped player stopped
+			if idx >= len(stations) || idx == p.currentStation || !p.Playing() {
+				p.stationMu.Unlock()
+				return
+			}
+			uiPrintln("\n🕒 Scheduled station")
+			switchLocked(idx)
+			p.stationMu.Unlock()
+			p.printPrompt()
+		})
+	}
+	if p.autoCurve != nil {
+		go p.runAutoVolume(statsCtx, func(percent int) {
+			p.stationMu.Lock()
+			p.setAutoVolume(percent, stations[p.currentStation].URL)
+			p.stationMu.Unlock()
+			p.printPrompt()
+		})
+	}
+
+	var (
+		alarmAt     time.Time
+		alarmCancel context.CancelFunc
+	)
+	defer func() {
+		if alarmCancel != nil {
+			alarmCancel()
+		}
+	}()
+
+	var shuffleCancel context.CancelFunc
+	stopShuffle := func() {
+		if shuffleCancel != nil {
+			shuffleCancel()
+			shuffleCancel = nil
+		}
+	}
+	defer stopShuffle()
+	// startShuffle switches to the station next picks every so often,
+	// staying put when it finds none. next gets a copy of the list and
+	// runs without stationMu, since health checks can take a while.
+	startShuffle := func(every time.Duration, next func(list []radio.Station, current int) (int, bool)) {
+		stopShuffle()
+		shuffleCtx, cancel := context.WithCancel(ctx)
+		shuffleCancel = cancel
+		go func() {
+			ticker := time.NewTicker(every)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-shuffleCtx.Done():
+					return
+				case <-ticker.C:
+					// A stopped player stays stopped until the user picks a station
+					if !p.Playing() {
+						continue
+					}
+					p.stationMu.Lock()
+					list, current := slices.Clone(stations), p.currentStation
+					p.stationMu.Unlock()
+					idx, ok := next(list, current)
+					if shuffleCtx.Err() != nil {
+						return
+					}
+					if !ok {
+						continue
+					}
+					p.stationMu.Lock()
+					// The list may have been edited meanwhile
+					if idx = stationIndex(stations, list[idx].URL); idx < 0 || idx == p.currentStation {
+						p.stationMu.Unlock()
+						continue
+					}
+					fmt.Println()
+					switchLocked(idx)
+					p.stationMu.Unlock()
+					p.printPrompt()
+				}
+			}
+		}()
+	}
+	// shuffleFavorites cycles through the favorites, as -shuffle-favs,
+	// and remembers it for the next run
+	shuffleFavorites := func(every time.Duration) {
+		if len(favoriteStations(stations, -1, p.savedRatings())) == 0 {
+			stopShuffle()
+			p.setShuffleFavs(0)
+			fmt.Printf("There are no favorites to shuffle through; rate stations %d or %d stars first\n", favoriteRating, maxRating)
+			return
+		}
+		p.setShuffleFavs(every)
+		fmt.Printf("Shuffling through your favorites every %s\n", every)
+		startShuffle(every, func(list []radio.Station, current int) (int, bool) {
+			idx, ok := p.nextFavorite(list, current)
+			if !ok {
+				uiPrintf("\n⚠️  No other favorite passed its health check; staying on %s\n", p.displayName(list[current].Name))
+				p.printPrompt()
+			}
+			return idx, ok
+		})
+	}
+	// endShuffle turns any shuffle off for good, leaving the current
+	// station playing
+	endShuffle := func() {
+		stopShuffle()
+		p.setShuffleFavs(0)
+		fmt.Println("Shuffle off")
+	}
 
-	reader := bufio.NewReader(os.Stdin)
-	fmt.Print("radio> ")
+	if p.shuffleFavs > 0 {
+		shuffleFavorites(p.shuffleFavs)
+	}
+
+	// A script gets plain lines: no editor, history, or terminal modes
+	var reader commandInput
+	if p.batch {
+		reader = newLineReader(os.Stdin)
+	} else {
+		reader = newCommandInput(historyFile)
+	}
+	defer reader.Close()
+	// readLine waits for a line of input, letting the goroutines switch
+	// stations meanwhile
+	readLine := func() (string, error) {
+		p.stationMu.Unlock()
+		defer p.stationMu.Lock()
+		return reader.Next(ctx)
+	}
+	// ask prompts for a line of a multi-step command. It's false once
+	// input has ended, after quitting.
+	ask := func(prompt string) (string, bool) {
+		if !p.batch {
+			fmt.Print(prompt)
+		}
+		line, err := readLine()
+		if ctx.Err() != nil {
+			return "", false
+		}
+		if errors.Is(err, io.EOF) && strings.TrimSpace(line) == "" {
+			fmt.Println()
+			p.quit()
+			return "", false
+		}
+		return strings.TrimSpace(line), true
+	}
+	p.printPrompt()
 	for {
-		line, err := reader.ReadString('\n')
-		if err != nil {
+		line, err := readLine()
+		// End of piped input: run a final unterminated line, then quit
+		eof := errors.Is(err, io.EOF)
+		if err != nil && !eof {
 			if ctx.Err() != nil {
+				fmt.Println()
 				return
 			}
 			fmt.Println("input error:", err)
 			return
 		}
 		input := strings.TrimSpace(line)
-		switch input {
-		case "q":
-			_ = p.Stop()
+		if eof && input == "" {
+			fmt.Println()
+			p.quit()
+			return
+		}
+		if len(input) > 1 {
+			// Single keys are quicker to retype than to recall
+			reader.Remember(input)
+		}
+		cmd, arg, _ := strings.Cut(input, " ")
+		arg = strings.TrimSpace(arg)
+		if input == "" && strings.TrimRight(line, "\r\n") != "" {
+			cmd = spaceKey
+		}
+		switch p.keys.action(cmd) {
+		case "quit":
+			p.quit()
 			return
-		case "h":
-			printHelp()
-		case "s":
+		case "help":
+			printHelp(p.keys, len(stations), p.volumeStep)
+		case "stop":
+			if arg == "shuffle" {
+				endShuffle()
+				break
+			}
 			_ = p.Stop()
-		case "v":
-			fmt.Print("Enter volume (0-100): ")
-			vline, _ := reader.ReadString('\n')
-			vline = strings.TrimSpace(vline)
+		case "play":
+			if p.Status().Playing {
+				fmt.Println("Already playing:", p.displayName(now.Name))
+				break
+			}
+			if err := p.startWithSpinner(func() error { return p.Start(now.URL) }); err != nil {
+				reportStartError(err, p.Backend())
+			} else {
+				uiPrintln("✓ Now playing:", p.displayName(now.Name))
+			}
+		case "pause":
+			if !p.Status().Paused {
+				if err := p.Pause(); err != nil {
+					fmt.Println("Nothing to pause:", err)
+					break
+				}
+				fmt.Printf("Paused; type %s to resume\n", cmd)
+				break
+			}
+			if err := p.startWithSpinner(p.Resume); err != nil {
+				reportStartError(err, p.Backend())
+			} else {
+				uiPrintln("✓ Now playing:", p.displayName(now.Name))
+			}
+		case "volume":
+			vline, ok := ask(fmt.Sprintf("Enter volume (0-%d): ", p.MaxVolume()))
+			if !ok {
+				return
+			}
 			var v int
 			fmt.Sscanf(vline, "%d", &v)
-			p.SetVolume(v)
-			fmt.Printf("Volume set to %d%%\n", p.volumePercent)
-			// restart if currently playing
-			if !p.isStopped {
-				_ = p.Restart(stations[p.currentStation].URL)
-			}
-		case "l":
-			listStations(stations)
+			p.changeVolume(v, stations[p.currentStation].URL)
+		case "volume-up":
+			p.changeVolume(p.Volume()+p.volumeStep, stations[p.currentStation].URL)
+		case "volume-down":
+			p.changeVolume(p.Volume()-p.volumeStep, stations[p.currentStation].URL)
+		case "eq":
+			if arg == "" {
+				eline, ok := ask(fmt.Sprintf("Enter EQ preset (%s) or freq:gain pairs: ", strings.Join(radio.EQPresetNames(), ", ")))
+				if !ok {
+					return
+				}
+				arg = eline
+			}
+			if err := p.SetEQ(arg); err != nil {
+				fmt.Println("EQ error:", err)
+				break
+			}
+			fmt.Println("EQ set to:", p.EQ())
+			p.persistState()
+			if p.Playing() {
+				if err := p.Restart(stations[p.currentStation].URL); err != nil {
+					reportStartError(err, p.Backend())
+				}
+			}
+		case "alarm":
+			switch arg {
+			case "":
+				if alarmAt.IsZero() || time.Now().After(alarmAt) {
+					fmt.Printf("No alarm set. Usage: %[1]s HH:MM | %[1]s off\n", cmd)
+				} else {
+					uiPrintln("⏰ Alarm set for", describeAlarm(alarmAt))
+				}
+			case "off":
+				if alarmCancel != nil {
+					alarmCancel()
+					alarmCancel = nil
+					alarmAt = time.Time{}
+				}
+				fmt.Println("Alarm off")
+			default:
+				at, err := nextAlarm(time.Now(), arg)
+				if err != nil {
+					fmt.Println("Alarm error:", err)
+					break
+				}
+				if alarmCancel != nil {
+					alarmCancel()
+				}
+				alarmCtx, cancel := context.WithCancel(ctx)
+				alarmCancel = cancel
+				alarmAt = at
+				uiPrintln("⏰ Alarm set for", describeAlarm(at))
+				go func() {
+					if !waitUntil(alarmCtx, at) {
+						return
+					}
+					p.stationMu.Lock()
+					st := stations[p.currentStation]
+					uiPrintln("\n⏰ Alarm! Playing:", p.displayName(st.Name))
+					p.SetRampIn(p.alarmRamp)
+					if err := p.Restart(st.URL); err != nil {
+						reportStartError(err, p.Backend())
+					}
+					p.stationMu.Unlock()
+					p.printPrompt()
+				}()
+			}
+		case "random":
+			switchLocked(randomStation(len(stations), p.currentStation))
+		case "next":
+			switchLocked((p.currentStation + 1) % len(stations))
+		case "prev":
+			switchLocked((p.currentStation + len(stations) - 1) % len(stations))
+		case "reveal":
+			p.reveal(now.Name)
+		case "recent":
+			if p.hidden() {
+				fmt.Printf("The list would give the station away; type %s first\n", p.keys.key("reveal"))
+				break
+			}
+			printRecent(recent.Visits(), stations, time.Now())
+		case "back":
+			prev, ok := recent.Back()
+			if !ok {
+				fmt.Println("No earlier station to go back to")
+				break
+			}
+			idx := stationIndex(stations, prev.URL)
+			if idx < 0 {
+				fmt.Printf("%s is no longer in the station list\n", prev.Name)
+				break
+			}
+			// switchLocked records the station again as the newest visit,
+			// which Visit folds into the entry Back left on top
+			switchLocked(idx)
+		case "shuffle":
+			if arg == "off" {
+				endShuffle()
+				break
+			}
+			count, option, _ := strings.Cut(arg, " ")
+			minutes, err := strconv.Atoi(count)
+			if err != nil || minutes < 1 || (option != "" && option != "rated" && option != "favs") {
+				fmt.Printf("Usage: %[1]s <minutes> [rated|favs] | %[1]s off\n", cmd)
+				break
+			}
+			every := time.Duration(minutes) * time.Minute
+			switch option {
+			case "favs":
+				shuffleFavorites(every)
+			case "rated":
+				p.setShuffleFavs(0)
+				fmt.Printf("Shuffling to a random station every %s, favoring higher-rated ones\n", every)
+				startShuffle(every, func(list []radio.Station, current int) (int, bool) {
+					return ratedRandomStation(list, current, p.savedRatings()), true
+				})
+			default:
+				p.setShuffleFavs(0)
+				fmt.Printf("Shuffling to a random station every %s\n", every)
+				startShuffle(every, func(list []radio.Station, current int) (int, bool) {
+					return randomStation(len(list), current), true
+				})
+			}
+		case "list":
+			if arg != "" && arg != "rating" {
+				fmt.Printf("Usage: %s [rating]\n", cmd)
+				break
+			}
+			listStations(stations, p.savedRatings(), arg == "rating")
+		case "rate":
+			n, err := strconv.Atoi(arg)
+			if err != nil || n < 0 || n > maxRating {
+				fmt.Printf("Usage: %[1]s N, with N from 1 to %[2]d stars (%[1]s 0 clears it)\n", cmd, maxRating)
+				break
+			}
+			st := stations[p.currentStation]
+			p.rate(st.URL, n)
+			if n == 0 {
+				fmt.Printf("Cleared the rating of %s\n", p.displayName(st.Name))
+				break
+			}
+			uiPrintf("Rated %s %s\n", p.displayName(st.Name), stars(n))
+		case "status":
+			printStatus(os.Stdout, p, stations[p.currentStation])
+		case "copy":
+			copyURL(p, stations[p.currentStation], arg)
+		case "open":
+			openStation(stations[p.currentStation])
+		case "check":
+			fmt.Printf("Checking %d stations...\n", len(stations))
+			printStationChecks(radio.CheckStations(stations, p.Analyzer().Client(), p.ResolveOptions()))
+		case "deps":
+			if err := radio.CheckDependencies(p.Backend()); err != nil {
+				fmt.Println("Error:", err)
+			} else {
+				uiPrintf("✓ %s and yt-dlp found\n", p.Backend())
+			}
+			if err := radio.CheckFFprobe(); err != nil {
+				fmt.Println("Warning:", err)
+			} else {
+				uiPrintln("✓ ffprobe found")
+			}
 		case "viz":
-			p.visualization = !p.visualization
 			state := "OFF"
-			if p.visualization {
+			if p.toggleVisualization() {
 				state = "ON"
 			}
 			fmt.Println("Visualization:", state)
-		case "1", "2", "3", "4", "5":
-			idx := int(input[0] - '1')
-			if idx >= 0 && idx < len(stations) {
-				p.currentStation = idx
-				now = stations[p.currentStation]
-				fmt.Println("Switching to:", now.Name)
-				if err := p.Restart(now.URL); err != nil {
-					fmt.Printf("Failed to start station: %v\n", err)
-				} else {
-					fmt.Println("✓ Now playing:", now.Name)
+		case "add":
+			name, ok := ask("Name: ")
+			if !ok {
+				return
+			}
+			url, ok := ask("URL: ")
+			if !ok {
+				return
+			}
+			desc, ok := ask("Description (optional): ")
+			if !ok {
+				return
+			}
+			st, err := newStation(name, url, desc)
+			if err != nil {
+				fmt.Println("Not added:", err)
+				break
+			}
+			if err := addConfigStation(configPath, st); err != nil {
+				fmt.Println("Not added:", err)
+				break
+			}
+			stations = append(stations, st)
+			fmt.Printf("Added [%d] %s\n", len(stations), st.Name)
+		case "edit":
+			idx, valid := stationNumber(arg, len(stations))
+			if !valid {
+				fmt.Printf("Usage: %s N, with N from 1 to %d\n", cmd, len(stations))
+				break
+			}
+			old := stations[idx]
+			fmt.Println("Press Enter to keep a value; type - to clear the description")
+			name, ok := ask(fmt.Sprintf("Name [%s]: ", old.Name))
+			if !ok {
+				return
+			}
+			url, ok := ask(fmt.Sprintf("URL [%s]: ", radio.RedactURL(old.URL)))
+			if !ok {
+				return
+			}
+			desc, ok := ask(fmt.Sprintf("Description [%s]: ", old.Description))
+			if !ok {
+				return
+			}
+			name, url = cmp.Or(name, old.Name), cmp.Or(url, old.URL)
+			switch desc {
+			case "":
+				desc = old.Description
+			case "-":
+				desc = ""
+			}
+			st, err := newStation(name, url, desc)
+			if err != nil {
+				fmt.Println("Not changed:", err)
+				break
+			}
+			if err := updateConfigStation(configPath, old, st); err != nil {
+				fmt.Println("Not changed:", err)
+				break
+			}
+			if st.URL != old.URL {
+				p.moveRating(old.URL, st.URL)
+			}
+			// Keep the station's other settings, such as headers
+			old.Name, old.URL, old.Description = st.Name, st.URL, st.Description
+			stations[idx] = old
+			fmt.Printf("Saved [%d] %s\n", idx+1, old.Name)
+			if idx == p.currentStation {
+				if now.URL != old.URL {
+					fmt.Printf("Type %d to play the new URL\n", idx+1)
 				}
-			} else {
-				fmt.Println("Invalid station number")
+				now = old
+			}
+		case "remove":
+			idx, valid := stationNumber(arg, len(stations))
+			if !valid {
+				fmt.Printf("Usage: %s N, with N from 1 to %d\n", cmd, len(stations))
+				break
+			}
+			if len(stations) == 1 {
+				fmt.Println("Can't remove the only station")
+				break
+			}
+			st := stations[idx]
+			prompt := fmt.Sprintf("Remove [%d] %s? [y/N] ", idx+1, st.Name)
+			if idx == p.currentStation {
+				prompt = fmt.Sprintf("[%d] %s is the current station. Remove it anyway? [y/N] ", idx+1, p.displayName(st.Name))
+			}
+			answer, ok := ask(prompt)
+			if !ok {
+				return
+			}
+			if answer = strings.ToLower(answer); answer != "y" && answer != "yes" {
+				fmt.Println("Kept", st.Name)
+				break
+			}
+			if err := removeConfigStation(configPath, st.URL); err != nil {
+				fmt.Println("Not removed:", err)
+				break
+			}
+			stations = slices.Delete(stations, idx, idx+1)
+			fmt.Printf("Removed %s; there are %d stations now\n", st.Name, len(stations))
+			switch {
+			case idx < p.currentStation:
+				p.currentStation--
+			case idx == p.currentStation:
+				p.currentStation = min(p.currentStation, len(stations)-1)
+				fmt.Println("It keeps playing until you switch stations")
+			}
+			p.persistState()
+		case "export":
+			if arg == "" {
+				fmt.Printf("Usage: %s PATH, e.g. %s session.md\n", cmd, cmd)
+				break
+			}
+			if err := p.exportReport(arg, time.Now()); err != nil {
+				fmt.Println("Export failed:", err)
+				break
+			}
+			fmt.Println("Wrote the session report to", arg)
+		case "reload":
+			cfg, warnings, err := loadConfig(configPath)
+			for _, w := range warnings {
+				fmt.Println("Config warning:", w)
+			}
+			if err != nil {
+				fmt.Println("Reload failed:", err)
+				break
+			}
+			playingURL := stations[p.currentStation].URL
+			stations = cfg.Stations
+			// Edited stations may point somewhere else now
+			p.Analyzer().ClearProbeCache()
+			fmt.Printf("Reloaded %d stations from %s\n", len(stations), configPath)
+			found := false
+			for i, s := range stations {
+				if s.URL == playingURL {
+					p.currentStation = i
+					found = true
+					break
+				}
+			}
+			if !found {
+				if p.currentStation >= len(stations) {
+					p.currentStation = len(stations) - 1
+				}
+				fmt.Println("Note: the current stream is no longer in the station list")
 			}
 		default:
-			if input != "" {
-				fmt.Println("Unknown command. Press 'h' for help.")
+			if n, err := strconv.Atoi(input); err == nil {
+				idx := n - 1
+				if idx >= 0 && idx < len(stations) {
+					switchLocked(idx)
+				} else {
+					fmt.Println("Invalid station number")
+				}
+			} else if input != "" {
+				if guess, ok := suggestCommand(cmd, p.keys.commands()); ok {
+					fmt.Printf("Unknown command %q, did you mean '%s'? Press '%s' for help.\n", cmd, guess, p.keys.key("help"))
+				} else {
+					fmt.Printf("Unknown command. Press '%s' for help.\n", p.keys.key("help"))
+				}
 			}
 		}
-		fmt.Print("radio> ")
+		if eof {
+			fmt.Println()
+			p.quit()
+			return
+		}
+		p.printPrompt()
 	}
 }
 
 func main() {
-	// Check dependencies first
-	if err := checkDependencies(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
-	}
-
 	var (
 		flagList        bool
 		flagInteractive bool
 		flagStation     int
 		flagVolume      int
+		flagEQ          string
+		flagFade        int
+		flagProxy       string
+		flagJSON        bool
+		flagStatsFormat string
+		flagConfig      string
+		flagFFplayArgs  string
+		flagNoReconnect bool
+		flagBackend     string
+		flagCheck       bool
+		flagScrobble    bool
+		flagDiscord     bool
+		flagNormalize   bool
+		flagLUFS        float64
+		flagNoColor     bool
+		flagVolumeStep  int
+		flagStatsEvery  time.Duration
+		flagDaemon      bool
+		flagSocket      string
+		flagCookies     string
+		flagCookiesFrom string
+		flagAlarm       string
+		flagAlarmRamp   time.Duration
+		flagQuality     string
+		flagVolumeCurve string
+		flagDataCap     int
+		flagDataCapStop bool
+		flagAdaptive    bool
+		flagSaveHistory bool
+		flagSaveRecent  bool
+		flagBlind       bool
+		flagStartPaused bool
+		flagDevice      string
+		flagListDevices bool
+		flagDryRun      bool
+		flagDryResolve  bool
+		flagTitles      bool
+		flagMarqueeW    int
+		flagMarqueeRate float64
+		flagLoop        bool
+		flagShuffleDir  bool
+		flagConnWait    time.Duration
+		flagSkipDead    bool
+		flagMono        bool
+		flagProbeEvery  time.Duration
+		flagLogLevel    string
+		flagLogFile     string
+		flagDetach      bool
+		flagOncePerLine bool
+		flagStop        bool
+		flagStationsURL string
+		flagMergeURL    bool
+		flagArt         bool
+		flagLowLatency  bool
+		flagSchedule    bool
+		flagPCM         bool
+		flagPrebuffer   float64
+		flagIPv4        bool
+		flagIPv6        bool
+		flagSort        string
+		flagAllowBoost  bool
+		flagHTTPAddr    string
+		flagQuiet       bool
+		flagSilence     time.Duration
+		flagSkipSilent  bool
+		flagShuffleFavs time.Duration
+		flagInitConfig  bool
+		flagForce       bool
+		flagNoMetaCache bool
+		flagAlertBell   bool
+		flagAlertNotify bool
+		flagVersion     bool
+		flagStdin       bool
+		flagAutoVolume  bool
+		flagReprobe     time.Duration
 	)
 	flag.BoolVar(&flagInteractive, "i", true, "interactive mode")
+	flag.BoolVar(&flagVersion, "version", false, "print the version, git commit, and build date, and exit")
 	flag.BoolVar(&flagList, "list", false, "list stations and exit")
-	flag.IntVar(&flagStation, "station", 1, "station number to start (1-5)")
-	flag.IntVar(&flagVolume, "volume", 70, "start volume 0-100")
-	flag.Parse()
+	flag.StringVar(&flagSort, "sort", "", "order for -list: rating puts the highest rated stations first")
+	flag.IntVar(&flagStation, "station", 1, "station number to start")
+	flag.IntVar(&flagVolume, "volume", 70, "start volume 0-100 (up to 150 with -allow-boost)")
+	flag.BoolVar(&flagAllowBoost, "allow-boost", false, "let the volume go past 100%, up to 150%, amplifying streams that are too quiet (loud passages may clip)")
+	flag.IntVar(&flagVolumeStep, "volume-step", 5, "volume change for the + and - commands")
+	flag.StringVar(&flagEQ, "eq", "flat", "EQ preset (flat, bass, treble, vocal) or freq:gain pairs")
+	flag.IntVar(&flagFade, "fade", 0, "fade in/out duration in milliseconds (0 disables)")
+	flag.StringVar(&flagProxy, "proxy", "", "proxy URL for stream connections (default from HTTP_PROXY/ALL_PROXY)")
+	flag.BoolVar(&flagJSON, "json", false, "print stats as one JSON object per line (same as -stats-format json)")
+	flag.StringVar(&flagStatsFormat, "stats-format", statsFull, "stats output: full, compact (one line), or json; compact and json imply -i=false")
+	flag.StringVar(&flagConfig, "config", "", "stations config file (default drift-radio/config.json in the user config dir)")
+	flag.BoolVar(&flagInitConfig, "init-config", false, "write an example config file, with the built-in stations, to -config's path and exit")
+	flag.BoolVar(&flagForce, "force", false, "let -init-config replace an existing config file")
+	flag.StringVar(&flagFFplayArgs, "ffplay-args", "", "extra arguments passed to the player (ffplay or mpv) before the stream URL")
+	flag.BoolVar(&flagNoReconnect, "no-reconnect", false, "don't pass ffmpeg's HTTP reconnect options to ffplay")
+	flag.StringVar(&flagBackend, "backend", radio.BackendFFplay, "playback backend: ffplay or mpv (mpv changes volume without restarting)")
+	flag.BoolVar(&flagCheck, "check", false, "check which stations are reachable and exit")
+	flag.BoolVar(&flagNormalize, "normalize", false, "even out loudness between stations with ffmpeg's loudnorm filter (adds a few seconds before audio starts)")
+	flag.Float64Var(&flagLUFS, "normalize-target", radio.DefaultLoudnessTarget, "integrated loudness target for -normalize, in LUFS (-70 to -5)")
+	flag.BoolVar(&flagDiscord, "discord", false, "show the playing station on Discord via Rich Presence (client_id from the config file)")
+	flag.BoolVar(&flagScrobble, "scrobble", false, "scrobble stream track titles to Last.fm (credentials from the config file)")
+	flag.BoolVar(&flagNoColor, "no-color", false, "plain ASCII output without emoji or escape codes (also NO_COLOR, or when stdout isn't a terminal)")
+	flag.DurationVar(&flagStatsEvery, "stats-interval", radio.DefaultStatsInterval, "how often to sample the stats; the display refreshes twice as often (min 200ms)")
+	flag.BoolVar(&flagDaemon, "daemon", false, "run headless and take commands on a control socket (see \"ctl\")")
+	flag.StringVar(&flagSocket, "socket", defaultSocketPath(), "control socket path for -daemon and ctl")
+	flag.StringVar(&flagHTTPAddr, "http-addr", "", "serve /healthz, /stats, and /nowplaying on this address for monitoring, e.g. :8080 (a bare port binds to localhost)")
+	flag.StringVar(&flagCookies, "cookies", "", "cookies file passed to yt-dlp --cookies (for age-restricted streams)")
+	flag.StringVar(&flagCookiesFrom, "cookies-from-browser", "", "browser passed to yt-dlp --cookies-from-browser, e.g. firefox or chrome")
+	flag.StringVar(&flagAlarm, "alarm", "", "wait until this time (HH:MM, 24-hour) before starting playback")
+	flag.DurationVar(&flagAlarmRamp, "alarm-ramp", DefaultAlarmRamp, "how long the alarm fades in from silence")
+	flag.StringVar(&flagQuality, "quality", "high", "audio quality for yt-dlp stations: low, medium, or high")
+	flag.StringVar(&flagVolumeCurve, "volume-curve", radio.CurveDB, "how volume maps to loudness with ffplay: db, perceptual, or linear")
+	flag.IntVar(&flagDataCap, "data-cap", 0, "warn when this session has downloaded this many MB (0 disables; Linux only)")
+	flag.BoolVar(&flagStartPaused, "start-paused", false, "resolve and connect the first station on launch but wait for the play command before starting audio")
+	flag.BoolVar(&flagSaveHistory, "save-history", false, "keep interactive command history between sessions")
+	flag.BoolVar(&flagSaveRecent, "save-recent", false, "keep the recently played stations list between sessions")
+	flag.BoolVar(&flagBlind, "blind", false, "hide each station's name (shown as ???) until the reveal command, for judging streams blind")
+	flag.BoolVar(&flagAdaptive, "adaptive", false, "lower the quality of yt-dlp stations while the network is poor, and raise it again when it recovers")
+	flag.BoolVar(&flagDataCapStop, "data-cap-stop", false, "stop playback when the -data-cap is reached")
+	flag.StringVar(&flagDevice, "device", "", "audio output device name from -list-devices (\"default\" resets a saved choice)")
+	flag.BoolVar(&flagListDevices, "list-devices", false, "list audio output devices for the backend and exit")
+	flag.BoolVar(&flagDryRun, "dry-run", false, "print the yt-dlp and player commands for the starting station instead of playing")
+	flag.BoolVar(&flagDryResolve, "dry-run-resolve", true, "with -dry-run, run yt-dlp so the player command shows the real media URL")
+	flag.BoolVar(&flagTitles, "titles", false, "show the stream's track title in the status line (reads a second copy of Icecast/SHOUTcast streams)")
+	flag.IntVar(&flagMarqueeW, "marquee-width", defaultMarqueeWidth, "terminal columns of the track title shown before it scrolls (CJK characters and emoji take two)")
+	flag.Float64Var(&flagMarqueeRate, "marquee-speed", defaultMarqueeSpeed, "track title scroll speed in characters per second")
+	flag.BoolVar(&flagLoop, "loop", false, "repeat files and other finite sources without a gap, and start directories over when they finish")
+	flag.BoolVar(&flagShuffleDir, "shuffle-tracks", false, "play the files of a directory station in random order")
+	flag.DurationVar(&flagConnWait, "connect-timeout", 15*time.Second, "give up on a stream whose server hasn't answered after this long (0 waits forever)")
+	flag.BoolVar(&flagSkipDead, "skip-dead", false, "move on to the next station when one doesn't answer within -connect-timeout")
+	flag.DurationVar(&flagSilence, "silence", 0, "report a stream that has been silent this long, as from a dead mount (reads a second copy of the stream; 0 disables)")
+	flag.BoolVar(&flagSkipSilent, "skip-silent", false, "move on to the next mirror or station when a stream goes silent (implies -silence 30s)")
+	flag.DurationVar(&flagShuffleFavs, "shuffle-favs", 0, "switch to a random favorite (a station rated 4 or 5 stars) this often, skipping any that fail a health check; kept on across runs until stop shuffle (0 turns it off)")
+	flag.BoolVar(&flagAlertBell, "alert-bell", false, "ring the terminal bell when a quality alert is raised")
+	flag.BoolVar(&flagAlertNotify, "alert-notify", false, "show a desktop notification when a quality alert is raised (notify-send on Linux, osascript on macOS)")
+	flag.BoolVar(&flagMono, "mono", false, "downmix the stream to one channel, for a single speaker or one ear")
+	flag.DurationVar(&flagProbeEvery, "probe-interval", radio.DefaultProbeInterval, "how often to send the stream's server a HEAD request for the network stats (min 1s)")
+	flag.BoolVar(&flagNoMetaCache, "no-probe-cache", false, "run ffprobe every time a station starts, instead of reusing its codec and bitrate from an earlier visit")
+	flag.DurationVar(&flagReprobe, "reprobe-interval", radio.DefaultReprobeInterval, "how often to run ffprobe on the playing stream again to catch codec, bitrate, or sample rate changes (min 1m; 0 turns it off; yt-dlp stations are skipped)")
+	flag.StringVar(&flagLogLevel, "log-level", "info", "lowest level of diagnostics to log: debug, info, warn, or error")
+	flag.StringVar(&flagLogFile, "log-file", "", "append diagnostics to this file instead of stderr")
+	flag.BoolVar(&flagOncePerLine, "once-per-line", false, "run the commands piped to stdin, one per line, without the prompt, help, or live stats, and exit at the end of the input")
+	flag.BoolVar(&flagQuiet, "quiet", false, "print nothing but errors, on stderr: no header, prompt, help, or stats, for scripts and services (implies -i=false)")
+	flag.BoolVar(&flagDetach, "detach", false, "keep the stream playing in the background after quitting (stop it with -stop)")
+	flag.BoolVar(&flagStop, "stop", false, "stop the stream a -detach session left playing and exit")
+	flag.StringVar(&flagStationsURL, "stations-url", "", "fetch the station list from this URL at startup (config file format; a cached copy is used when offline)")
+	flag.BoolVar(&flagStdin, "stdin", false, "read the station list from stdin, one \"Name|URL\" or bare URL per line, then take commands from the terminal")
+	flag.BoolVar(&flagMergeURL, "stations-merge", false, "add the -stations-url stations to the config file's instead of replacing them")
+	flag.BoolVar(&flagArt, "art", false, "show the station's artwork or video thumbnail above the stats, in terminals with inline images (kitty, iTerm2, WezTerm)")
+	flag.BoolVar(&flagAutoVolume, "auto-volume", false, "follow the volume curve by time of day in the config file's auto_volume list; a volume set by hand holds until the curve's next point")
+	flag.BoolVar(&flagSchedule, "schedule", false, "switch stations by time of day, following the schedule in the config file")
+	flag.BoolVar(&flagPCM, "pcm", false, "write the audio to stdout as raw PCM (s16le, 44.1 kHz, stereo) for another program, instead of playing it")
+	flag.BoolVar(&flagIPv4, "ipv4", false, "connect over IPv4 only, for yt-dlp and the stats probes (the player itself follows the system's preference)")
+	flag.BoolVar(&flagIPv6, "ipv6", false, "connect over IPv6 only, for yt-dlp and the stats probes (the player itself follows the system's preference)")
+	flag.Float64Var(&flagPrebuffer, "prebuffer", 0, "seconds of audio to buffer before a stream starts playing, for flaky connections (0 disables)")
+	flag.BoolVar(&flagLowLatency, "low-latency", false, "start streams with minimal buffering for less delay, at the cost of more dropouts on a poor network")
+	// flag's own exit code for bad flags, 2, is taken by exitDependency
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError)
+	if err := flag.CommandLine.Parse(os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(exitOK)
+		}
+		os.Exit(exitError)
+	}
+	if flagVersion {
+		fmt.Println(versionText())
+		return
+	}
+	// -pcm keeps stdout for the audio; everything else goes to stderr
+	pcmOut := os.Stdout
+	if flagPCM {
+		if fi, err := os.Stdout.Stat(); err == nil && fi.Mode()&os.ModeCharDevice != 0 && !flagDryRun {
+			fmt.Fprintln(os.Stderr, "Error: -pcm writes raw audio; pipe it to another program, e.g. drift-radio -pcm | aplay -f cd")
+			os.Exit(exitError)
+		}
+		if flagDaemon || flagDetach {
+			fmt.Fprintln(os.Stderr, "Error: -pcm can't be used with -daemon or -detach")
+			os.Exit(exitError)
+		}
+		os.Stdout = os.Stderr
+		flagInteractive = false
+	}
+	// -quiet drops everything bound for stdout; errors go to stderr as
+	// always, and the exit code tells the rest
+	if flagQuiet {
+		devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+		os.Stdout = devNull
+		flagInteractive = false
+		// Warnings are chatter too, unless asked for
+		logLevelSet := false
+		flag.Visit(func(f *flag.Flag) { logLevelSet = logLevelSet || f.Name == "log-level" })
+		if !logLevelSet {
+			flagLogLevel = "error"
+		}
+	}
+	plainOutput = detectPlainOutput(flagNoColor)
 
-	p := NewPlayer()
+	// Runs last, after the other deferred cleanup. Goroutines that end
+	// the run set exitCode and cancel the context rather than exit, so
+	// the scrobbler, Discord, and the log are closed first.
+	var interrupted atomic.Bool
+	var exitCode atomic.Int32
+	defer func() {
+		if interrupted.Load() {
+			os.Exit(exitInterrupted)
+		}
+		if code := exitCode.Load(); code != 0 {
+			os.Exit(int(code))
+		}
+	}()
+
+	logLevel, err := parseLogLevel(flagLogLevel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	logFile, err := setupLogging(logLevel, flagLogFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: log file: %v\n", err)
+		os.Exit(exitError)
+	}
+	defer logFile.Close()
+
+	// "drift-radio ctl <command>" talks to a running daemon
+	if flag.Arg(0) == "ctl" {
+		command := strings.Join(flag.Args()[1:], " ")
+		if command == "" {
+			fmt.Fprintln(os.Stderr, "Usage: drift-radio ctl play [N] | next | stop | vol N | status | quit")
+			os.Exit(exitError)
+		}
+		if err := sendControl(flagSocket, command); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+		return
+	}
+
+	if flagInitConfig {
+		path := flagConfig
+		if path == "" {
+			if path, err = defaultConfigPath(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(exitConfig)
+			}
+		}
+		if err := writeExampleConfig(path, flagForce); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitConfig)
+		}
+		fmt.Println("Wrote an example config to", path)
+		return
+	}
+
+	if flagStop {
+		d, stopped, err := stopDetached()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+		if !stopped {
+			fmt.Println("Nothing is playing in the background")
+			return
+		}
+		fmt.Println("Stopped", d.Station)
+		return
+	}
+
+	if flagBackend != radio.BackendFFplay && flagBackend != radio.BackendMPV {
+		fmt.Fprintf(os.Stderr, "Error: unknown backend %q (use ffplay or mpv)\n", flagBackend)
+		os.Exit(exitError)
+	}
+
+	var alarmAt time.Time
+	if flagAlarm != "" {
+		var err error
+		if alarmAt, err = nextAlarm(time.Now(), flagAlarm); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+	}
+
+	if flagPCM {
+		flagBackend = radio.BackendPCM
+	}
+	if flagPrebuffer < 0 || flagPrebuffer > maxPrebuffer {
+		fmt.Fprintf(os.Stderr, "Error: -prebuffer must be between 0 and %d seconds\n", maxPrebuffer)
+		os.Exit(exitError)
+	}
+	if flagSilence < 0 {
+		fmt.Fprintln(os.Stderr, "Error: -silence must not be negative")
+		os.Exit(exitError)
+	}
+	if flagSkipSilent && flagSilence == 0 {
+		flagSilence = defaultSilence
+	}
+	if flagShuffleFavs != 0 && flagShuffleFavs < time.Minute {
+		fmt.Fprintln(os.Stderr, "Error: -shuffle-favs must be at least 1m, or 0 to turn it off")
+		os.Exit(exitError)
+	}
+
+	// Check dependencies first; a dry run only prints the commands
+	if err := radio.CheckDependencies(flagBackend); err != nil && !flagDryRun {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitDependency)
+	}
+	// ffplay's pre-roll is decoded by ffmpeg
+	if flagPrebuffer > 0 && flagBackend == radio.BackendFFplay {
+		if err := radio.CheckDependencies(radio.BackendPCM); err != nil && !flagDryRun {
+			fmt.Fprintf(os.Stderr, "Error: -prebuffer: %v\n", err)
+			os.Exit(exitDependency)
+		}
+	}
+	// ffmpeg listens for the silence
+	if flagSilence > 0 && flagBackend != radio.BackendPCM {
+		if err := radio.CheckDependencies(radio.BackendPCM); err != nil && !flagDryRun {
+			fmt.Fprintf(os.Stderr, "Error: -silence: %v\n", err)
+			os.Exit(exitDependency)
+		}
+	}
+	if err := radio.CheckFFprobe(); err != nil && !flagDryRun {
+		slog.Warn(err.Error())
+	}
+
+	// Saved state fills in anything not given explicitly on the command line
+	setFlags := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
+	state, saved, err := loadState()
+	if err != nil {
+		slog.Warn("could not load state", "err", err)
+	}
+	if saved {
+		if !setFlags["station"] {
+			flagStation = state.Station + 1
+		}
+		if !setFlags["volume"] {
+			flagVolume = state.Volume
+		}
+		if !setFlags["eq"] {
+			flagEQ = state.EQ
+		}
+		if !setFlags["quality"] && state.Quality != "" {
+			flagQuality = state.Quality
+		}
+		if !setFlags["device"] {
+			flagDevice = state.Device
+		}
+		if !setFlags["mono"] {
+			flagMono = state.Mono
+		}
+		if !setFlags["shuffle-favs"] {
+			// A bad hand edit leaves it off
+			if d, err := time.ParseDuration(state.ShuffleFavs); err == nil && d >= time.Minute {
+				flagShuffleFavs = d
+			}
+		}
+	}
+
+	volumeCurve, err := radio.ParseVolumeCurve(flagVolumeCurve)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	opts := []radio.Option{
+		radio.WithBackend(flagBackend),
+		radio.WithVolumeCurve(volumeCurve),
+		radio.WithReconnect(!flagNoReconnect),
+		radio.WithLoop(flagLoop),
+		radio.WithShuffle(flagShuffleDir),
+	}
+	if flagDevice != "default" {
+		opts = append(opts, radio.WithDevice(flagDevice))
+	}
+	if flagConnWait > 0 {
+		opts = append(opts, radio.WithConnectTimeout(flagConnWait))
+	}
+	if flagDryRun {
+		opts = append(opts, radio.WithDryRun(os.Stdout, flagDryResolve))
+	}
+	if flagLowLatency {
+		opts = append(opts, radio.WithLowLatency())
+	}
+	if flagPCM {
+		opts = append(opts, radio.WithPCM(pcmOut))
+	}
+	if flagAllowBoost {
+		opts = append(opts, radio.WithVolumeBoost())
+	}
+	if flagQuiet {
+		opts = append(opts, radio.WithQuiet())
+	}
+	if flagSilence > 0 {
+		opts = append(opts, radio.WithSilenceDetect(flagSilence, flagSkipSilent))
+	}
+	if flagPrebuffer > 0 {
+		opts = append(opts, radio.WithPrebuffer(time.Duration(flagPrebuffer*float64(time.Second))))
+	}
+	if flagDetach && !flagDryRun {
+		opts = append(opts, radio.WithDetach())
+		// This session's stream takes over from one left playing earlier
+		if d, stopped, err := stopDetached(); err != nil {
+			slog.Warn("could not stop the detached player", "err", err)
+		} else if stopped {
+			fmt.Println("Stopped", d.Station, "(left playing by an earlier session)")
+		}
+	}
+	p := newPlayer(opts...)
+	p.loadRatings(state.Ratings)
+	p.detach = flagDetach
+	p.batch = flagOncePerLine
+	p.blind = flagBlind
+	if err := p.Analyzer().SetInterval(flagStatsEvery); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	if err := p.Analyzer().SetProbeInterval(flagProbeEvery); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	if err := p.Analyzer().SetReprobeInterval(flagReprobe); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	p.Analyzer().OnFormatChange(func(change string) {
+		slog.Info("stream format changed", "change", change)
+	})
+	if flagNoMetaCache {
+		p.Analyzer().SetProbeCache(0)
+	}
+	p.alertBell = flagAlertBell
+	p.alertNotify = flagAlertNotify
+	p.statsInterval = flagStatsEvery
+	p.connectTimeout = flagConnWait
+	p.skipDead = flagSkipDead
+	p.silenceAfter = flagSilence
+	p.skipSilent = flagSkipSilent
+	p.shuffleFavs = flagShuffleFavs
+	if flagAlarmRamp >= 0 {
+		p.alarmRamp = flagAlarmRamp
+	}
+	if flagVolumeStep > 0 {
+		p.volumeStep = flagVolumeStep
+	}
 	p.SetVolume(flagVolume)
+	p.baseVolume = p.Volume()
+	p.SetFade(time.Duration(flagFade) * time.Millisecond)
+	proxy, err := radio.ParseProxy(flagProxy)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	if proxy != nil && !radio.IsHTTPProxy(proxy) {
+		slog.Warn("ffplay only supports HTTP proxies; audio will connect directly while yt-dlp and stats use the SOCKS proxy")
+	}
+	p.SetProxy(proxy)
+	switch {
+	case flagIPv4 && flagIPv6:
+		fmt.Fprintln(os.Stderr, "Error: -ipv4 and -ipv6 can't be used together")
+		os.Exit(exitError)
+	case flagIPv4:
+		p.SetIPVersion(radio.IPv4)
+	case flagIPv6:
+		p.SetIPVersion(radio.IPv6)
+	}
+	if flagJSON {
+		flagStatsFormat = statsJSON
+	}
+	switch flagStatsFormat {
+	case statsFull, statsCompact, statsJSON:
+		p.statsFormat = flagStatsFormat
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown stats format %q (use full, compact, or json)\n", flagStatsFormat)
+		os.Exit(exitError)
+	}
+	lineStats := p.statsFormat != statsFull
+	if flagArt && !lineStats {
+		if p.artProtocol = detectImageProtocol(); p.artProtocol == "" {
+			slog.Debug("-art: the terminal doesn't show inline images")
+		}
+	}
+	if err := p.SetExtraArgs(flagFFplayArgs); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	if flagDataCap > 0 {
+		p.setDataCap(int64(flagDataCap)*1024*1024, flagDataCapStop)
+	}
+	p.SetQuality(flagQuality)
+	if flagNormalize {
+		if err := p.SetNormalize(flagLUFS); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+	}
+	p.SetMono(flagMono)
+	if err := p.SetEQ(flagEQ); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	// Stations come from the config file when there is one
+	stations := defaultStations
+	var cfg Config
+	configPath := flagConfig
+	if configPath == "" {
+		if configPath, err = defaultConfigPath(); err != nil {
+			slog.Warn("could not locate config directory", "err", err)
+		}
+	}
+	if configPath != "" {
+		loaded, warnings, err := loadConfig(configPath)
+		for _, w := range warnings {
+			slog.Warn("config: "+w, "file", configPath)
+		}
+		switch {
+		case err == nil:
+			cfg = loaded
+			stations = cfg.Stations
+		case flagConfig != "" || !errors.Is(err, os.ErrNotExist):
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitConfig)
+		}
+	}
+	if cfg.Stations != nil {
+		p.configPath = configPath
+	}
+	p.flags = commandLineFlags()
+	if p.keys, err = newKeymap(cfg.Keybindings); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s: %v\n", configPath, err)
+		os.Exit(exitConfig)
+	}
+	if flagStationsURL != "" && flag.Arg(0) == "" && !flagStdin {
+		from := radio.RedactURL(flagStationsURL)
+		remote, warnings, err := fetchStations(p.Analyzer().Client(), flagStationsURL)
+		if err != nil {
+			slog.Warn("could not fetch stations", "url", from, "err", err)
+			remote, warnings, err = cachedStations(flagStationsURL)
+			from = "the cached copy of " + from
+		}
+		for _, w := range warnings {
+			slog.Warn("stations: "+w, "from", from)
+		}
+		if err != nil {
+			slog.Warn("keeping the local station list", "err", err)
+		} else {
+			stations = mergeStations(stations, remote, flagMergeURL)
+			slog.Info(fmt.Sprintf("loaded %d stations from %s", len(remote), from))
+		}
+	}
+	// A URL, file, or directory on the command line is played on its own
+	if arg := flag.Arg(0); arg != "" {
+		u, _, err := radio.NormalizeStationURL(arg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+		stations = []radio.Station{{Name: filepath.Base(u), URL: u}}
+		flagStation = 1
+	}
+	// A piped-in list replaces the others; the pipe is spent afterwards, so
+	// commands come from the terminal
+	if flagStdin {
+		if flag.Arg(0) != "" {
+			fmt.Fprintln(os.Stderr, "Error: -stdin can't be used with a station argument")
+			os.Exit(exitError)
+		}
+		list, warnings, tty, err := readStdinStations()
+		for _, w := range warnings {
+			slog.Warn("stdin: " + w)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitConfig)
+		}
+		stations = list
+		if !setFlags["station"] {
+			flagStation = 1
+		}
+		if !tty && flagInteractive {
+			slog.Warn("no terminal to take commands from; playing without interactive mode")
+			flagInteractive = false
+		}
+	}
+	if flagOncePerLine && (flagStdin || flagDaemon || !flagInteractive || lineStats) {
+		fmt.Fprintln(os.Stderr, "Error: -once-per-line reads commands in interactive mode; it can't be used with -stdin, -daemon, -quiet, -pcm, -i=false, or compact or json stats")
+		os.Exit(exitError)
+	}
+
+	// Cookies are forwarded verbatim to yt-dlp; the flags override the config
+	if flagCookies == "" {
+		flagCookies = cfg.Cookies
+	}
+	if flagCookiesFrom == "" {
+		flagCookiesFrom = cfg.CookiesFromBrowser
+	}
+	if flagCookies != "" {
+		f, err := os.Open(flagCookies)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: cookies file: %v\n", err)
+			os.Exit(exitError)
+		}
+		f.Close()
+	}
+	p.SetCookies(flagCookies, flagCookiesFrom)
+
+	if flagListDevices {
+		devices, err := radio.ListAudioDevices(p.Backend())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+		printAudioDevices(devices, p.Device())
+		return
+	}
 
 	if flagList {
-		listStations(defaultStations)
+		if flagSort != "" && flagSort != "rating" {
+			fmt.Fprintf(os.Stderr, "Error: unknown -sort %q (use rating)\n", flagSort)
+			os.Exit(exitError)
+		}
+		listStations(stations, p.savedRatings(), flagSort == "rating")
+		return
+	}
+
+	if flagCheck {
+		if !printStationChecks(radio.CheckStations(stations, p.Analyzer().Client(), p.ResolveOptions())) {
+			os.Exit(exitUnreachable)
+		}
 		return
 	}
 
 	startIdx := flagStation - 1
-	if startIdx < 0 || startIdx >= len(defaultStations) {
+	if startIdx < 0 || startIdx >= len(stations) {
 		startIdx = 0
 	}
+	if flagSchedule {
+		rules, warnings := parseSchedule(cfg.Schedule, len(stations))
+		for _, w := range warnings {
+			slog.Warn("config: "+w, "file", configPath)
+		}
+		if len(rules) == 0 {
+			fmt.Fprintln(os.Stderr, "Error: -schedule needs a \"schedule\" list in the config file, e.g. [{\"after\": \"08:00\", \"station\": 1}]")
+			os.Exit(exitConfig)
+		}
+		p.schedule = rules
+		// The schedule picks the starting station
+		startIdx = activeRule(rules, time.Now()).station
+		slog.Info("schedule: " + describeSchedule(rules, stations[startIdx].Name, time.Now()))
+	}
+	if flagAutoVolume {
+		curve, warnings := parseVolumeCurve(cfg.AutoVolume, p.MaxVolume())
+		for _, w := range warnings {
+			slog.Warn("config: "+w, "file", configPath)
+		}
+		if len(curve) == 0 {
+			fmt.Fprintln(os.Stderr, "Error: -auto-volume needs an \"auto_volume\" list in the config file, e.g. [{\"hour\": 8, \"volume\": 70}, {\"hour\": 23, \"volume\": 20}]")
+			os.Exit(exitConfig)
+		}
+		p.startAutoVolume(curve, time.Now())
+		slog.Info("auto volume: " + describeAutoVolume(curve, time.Now()))
+	}
+	p.currentStation = startIdx
+	if flagDryRun {
+		p.applyStation(stations[startIdx])
+		if err := p.Start(stations[startIdx].URL); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(startExitCode(err))
+		}
+		return
+	}
+	if setFlags["quality"] || setFlags["device"] || setFlags["mono"] || setFlags["shuffle-favs"] {
+		// Remember explicit quality, device, mono, and shuffle choices for
+		// the next run
+		p.persistState()
+	}
+	if setFlags["shuffle-favs"] && flagShuffleFavs > 0 && (!flagInteractive || lineStats || flagDaemon) {
+		slog.Warn("-shuffle-favs only shuffles in interactive mode")
+	}
+
+	var scrobbler *Scrobbler
+	if flagScrobble {
+		if scrobbler, err = NewScrobbler(cfg.LastFM, p.Analyzer().Client()); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitConfig)
+		}
+		defer scrobbler.Close()
+	}
+	var presence *DiscordPresence
+	if flagDiscord {
+		if presence, err = NewDiscordPresence(cfg.Discord); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitConfig)
+		}
+		p.presence = presence
+		defer presence.Close()
+	}
+	p.title = newMarquee(flagMarqueeW, flagMarqueeRate)
+	if scrobbler != nil || presence != nil || flagTitles {
+		// The status line reads the title from the stats as it scrolls
+		p.Analyzer().SetTitleHandler(func(title string) {
+			if scrobbler != nil {
+				scrobbler.TitleChanged(title)
+			}
+			if presence != nil {
+				if !p.hidden() {
+					presence.TitleChanged(title)
+				}
+			}
+		})
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
+	p.SetContext(ctx)
+	if flagHTTPAddr != "" {
+		addr, err := httpListenAddr(flagHTTPAddr)
+		if err == nil {
+			err = serveHTTP(ctx, p, addr)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -http-addr: %v\n", err)
+			os.Exit(exitError)
+		}
+	}
+	if flagAdaptive {
+		go p.RunAdaptive(ctx, p.statsInterval, p.reportAdaptive)
+	}
 
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	if flagDetach {
+		// Closing the terminal is a way to quit, too
+		signal.Notify(sig, syscall.SIGHUP)
+	}
 	go func() {
 		<-sig
-		_ = p.Stop()
+		interrupted.Store(true)
+		if !p.detachOnQuit() {
+			_ = p.StopNow()
+		}
+		if scrobbler != nil {
+			scrobbler.Close()
+		}
+		if presence != nil {
+			presence.Close()
+		}
 		cancel()
 	}()
 
-	if flagInteractive {
-		interactiveMode(ctx, p, defaultStations, startIdx)
+	if !alarmAt.IsZero() {
+		uiPrintf("⏰ Alarm set for %s. Press Ctrl+C to cancel.\n", describeAlarm(alarmAt))
+		if !waitUntil(ctx, alarmAt) {
+			return
+		}
+		p.SetRampIn(p.alarmRamp)
+	}
+
+	if flagDaemon {
+		if err := runDaemon(ctx, p, stations, startIdx, flagSocket); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+		return
+	}
+
+	if flagInteractive && !lineStats {
+		var historyFile string
+		if flagSaveHistory {
+			if historyFile, err = historyPath(); err != nil {
+				slog.Warn("could not locate command history", "err", err)
+			}
+		}
+		var recentFile string
+		if flagSaveRecent {
+			if recentFile, err = recentPath(); err != nil {
+				slog.Warn("could not locate recently played stations", "err", err)
+			}
+		}
+		interactiveMode(ctx, p, stations, startIdx, configPath, historyFile, recentFile, flagStartPaused)
 		return
 	}
 
 	// Standard mode: start and wait until Ctrl+C
-	st := defaultStations[startIdx]
-	printHeader(p.volumePercent, st.Name)
-	if err := p.Start(st.URL); err != nil {
-		fmt.Println("Failed to start:", err)
-		os.Exit(1)
+	st := stations[startIdx]
+	// giveUp ends the run with code once main's deferred cleanup is done
+	giveUp := func(code int) {
+		_ = p.StopNow()
+		exitCode.Store(int32(code))
+		cancel()
+	}
+	// play switches to station idx. One that won't start, such as a page
+	// yt-dlp can't resolve, is skipped like one that doesn't answer.
+	var play func(idx int)
+	play = func(idx int) {
+		p.currentStation = idx
+		st := stations[idx]
+		p.applyStation(st)
+		fmt.Println("Switching to:", p.displayName(st.Name))
+		err := p.Start(st.URL)
+		if err == nil {
+			return
+		}
+		fmt.Fprintln(os.Stderr, "Failed to start:", err)
+		if !p.skipDeadStation(stations, play) {
+			giveUp(startExitCode(err))
+		}
+	}
+	p.OnUnreachable(func(string) {
+		// The schedule may be switching at the same time
+		p.stationMu.Lock()
+		defer p.stationMu.Unlock()
+		if !p.unreachable(stations, play) {
+			if flagQuiet {
+				// The warning went to stdout with everything else
+				fmt.Fprintf(os.Stderr, "Error: %s\n", p.deadReason(stations[p.currentStation]))
+			}
+			giveUp(exitUnreachable)
+		}
+	})
+	if flagPCM {
+		// Done when the stream ends or the program reading it goes away
+		p.OnChange(func(st radio.PlayerStatus) {
+			p.stateChanged(st)
+			if !st.Playing {
+				cancel()
+			}
+		})
+	}
+	p.applyStation(st)
+	if !lineStats && !flagQuiet {
+		printHeader(p.Volume(), p.displayName(st.Name))
+	}
+	if lineStats || flagPCM || flagQuiet {
+		err = p.Start(st.URL)
+	} else {
+		err = p.startWithSpinner(func() error { return p.Start(st.URL) })
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to start:", err)
+		if hint := radio.ResolveHint(err); hint != "" {
+			fmt.Fprintln(os.Stderr, "Try:", hint)
+		}
+		os.Exit(startExitCode(err))
+	}
+	if !lineStats && !flagPCM && !flagQuiet {
+		printHelp(p.keys, len(stations), p.volumeStep)
 	}
-	printHelp()
 
-	// Start real-time stats display
+	// Start real-time stats display; -pcm keeps to one-line stats, if any,
+	// on stderr
 	statsCtx, statsCancel := context.WithCancel(ctx)
 	defer statsCancel()
-	go p.displayStatsLoop(statsCtx)
+	if (lineStats || !flagPCM) && !flagQuiet {
+		go p.displayStatsLoop(statsCtx)
+	}
+
+	if p.schedule != nil {
+		go p.runSchedule(statsCtx, func(idx int) {
+			p.stationMu.Lock()
+			defer p.stationMu.Unlock()
+			if idx == p.currentStation || !p.Playing() {
+				return
+			}
+			p.currentStation = idx
+			st := stations[idx]
+			p.applyStation(st)
+			uiPrintln("🕒 Scheduled station")
+			fmt.Println("Switching to:", p.displayName(st.Name))
+			if err := p.Restart(st.URL); err != nil {
+				fmt.Fprintln(os.Stderr, "Failed to start:", err)
+			}
+		})
+	}
+	if p.autoCurve != nil {
+		go p.runAutoVolume(statsCtx, func(percent int) {
+			p.stationMu.Lock()
+			defer p.stationMu.Unlock()
+			p.setAutoVolume(percent, stations[p.currentStation].URL)
+		})
+	}
 
 	<-ctx.Done()
 }
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"io"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/hhaidrr/cli-radio-player/pkg/radio"
+)
+
+// TestDisplayTogglesRace flips the display settings while other goroutines
+// read them, the way the command loop, the stats display, and the station
+// switching goroutines do. It's meant for go test -race.
+func TestDisplayTogglesRace(t *testing.T) {
+	// The status line draws straight to stdout
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	stdout := os.Stdout
+	os.Stdout = devNull
+	defer func() { os.Stdout = stdout }()
+
+	p := newPlayer()
+	p.blind = true
+	stations := []radio.Station{{Name: "One", URL: "http://one.example/"}, {Name: "Two", URL: "http://two.example/"}}
+	// Enough rounds that the goroutines are preempted and interleave, even
+	// on one CPU
+	const rounds = 20000
+
+	// All at once, so they overlap
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	run := func(f func(i int)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			for i := range rounds {
+				f(i)
+			}
+		}()
+	}
+	// viz
+	run(func(int) { p.toggleVisualization() })
+	// reveal, and a station switch hiding the name again
+	run(func(i int) {
+		if i%2 == 0 {
+			p.revealed.Store(true)
+		} else {
+			p.revealed.Store(false)
+		}
+	})
+	// Station switches, as the shuffle and schedule goroutines make them
+	run(func(i int) {
+		p.stationMu.Lock()
+		p.currentStation = i % len(stations)
+		p.stationMu.Unlock()
+	})
+	// The status line coming and going, as interactive mode sets it up
+	run(func(i int) {
+		p.mu.Lock()
+		if i%2 == 0 {
+			p.statusLine = &statusLine{rows: 24, cols: 80, resized: make(chan struct{}, 1)}
+		} else {
+			p.statusLine = nil
+		}
+		p.mu.Unlock()
+	})
+	// The stats display
+	run(func(int) {
+		p.refreshStatusLine(p.Status())
+		_ = p.resized()
+	})
+	run(func(int) { p.redrawStatusLine() })
+	// A volume change, asking where to show it
+	run(func(int) { _ = p.hasStatusLine() })
+	// status, from the command loop
+	run(func(int) {
+		p.stationMu.Lock()
+		printStatus(io.Discard, p, stations[p.currentStation])
+		p.stationMu.Unlock()
+		_ = p.displayName(stations[0].Name)
+	})
+	close(start)
+	wg.Wait()
+
+	// An even number of toggles leaves viz as it started
+	if p.visualization.Load() {
+		t.Error("viz is on after an even number of toggles")
+	}
+}
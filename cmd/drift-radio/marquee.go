@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+// Marquee defaults for the status line's track title
+const (
+	defaultMarqueeWidth = 40
+	defaultMarqueeSpeed = 4.0 // characters per second
+	marqueePause        = 2 * time.Second
+	marqueeGap          = "   "
+)
+
+// marquee scrolls text that doesn't fit in width terminal columns from
+// right to left, holding still at the start of each cycle so it can be read. The
+// position depends only on the time since the text was set, so it can be
+// rendered at any rate.
+type marquee struct {
+	text  []rune
+	width int
+	speed float64 // characters per second
+	since time.Time
+}
+
+func newMarquee(width int, speed float64) *marquee {
+	return &marquee{width: max(width, 1), speed: speed}
+}
+
+// Set replaces the text, starting a new cycle when it changed
+func (m *marquee) Set(text string, now time.Time) {
+	if text == string(m.text) {
+		return
+	}
+	m.text = []rune(text)
+	m.since = now
+}
+
+// Render returns the visible window at now
+func (m *marquee) Render(now time.Time) string {
+	if stringWidth(string(m.text)) <= m.width || m.speed <= 0 {
+		return string(m.text)
+	}
+	loop := append(append([]rune(nil), m.text...), []rune(marqueeGap)...)
+	scroll := time.Duration(float64(len(loop)) / m.speed * float64(time.Second))
+	t := now.Sub(m.since) % (marqueePause + scroll)
+	offset := 0
+	if t > marqueePause {
+		offset = int((t - marqueePause).Seconds() * m.speed)
+	}
+	doubled := append(loop, loop...)
+	// Wide characters take two of the width's columns
+	window, _ := cutWidth(string(doubled[offset:]), m.width)
+	return strings.TrimRight(window, " ")
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMarqueeWideText(t *testing.T) {
+	start := time.Unix(0, 0)
+	m := newMarquee(20, 4)
+	// 10 CJK characters fill the 20 columns exactly
+	m.Set("東京の夜に流れる音楽", start)
+	if got := m.Render(start.Add(10 * time.Second)); got != "東京の夜に流れる音楽" {
+		t.Errorf("a title that fits scrolled: %q", got)
+	}
+
+	m.Set("東京の夜に流れる音楽と雨の歌", start)
+	for s := 0; s < 20; s++ {
+		now := start.Add(marqueePause + time.Duration(s)*250*time.Millisecond)
+		if w := stringWidth(m.Render(now)); w > 20 {
+			t.Fatalf("window at %v is %d columns wide, want at most 20", now.Sub(start), w)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"cmp"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"strings"
+
+	"github.com/hhaidrr/cli-radio-player/pkg/radio"
+)
+
+// maxRating is the most stars rate gives
+const maxRating = 5
+
+// unratedWeight is how an unrated station counts when shuffle prefers
+// higher-rated ones: as if it had three stars
+const unratedWeight = 3
+
+// stars draws a rating as filled and empty stars, or "" when unrated
+func stars(rating int) string {
+	if rating <= 0 {
+		return ""
+	}
+	return strings.Repeat("★", rating) + strings.Repeat("☆", maxRating-rating)
+}
+
+// rating returns the stars given to the station at url; 0 when unrated
+func (p *Player) rating(url string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.ratings[url]
+}
+
+// rate gives the station at url n stars, or forgets its rating for 0,
+// and saves it
+func (p *Player) rate(url string, n int) {
+	p.mu.Lock()
+	if p.ratings == nil {
+		p.ratings = map[string]int{}
+	}
+	if n == 0 {
+		delete(p.ratings, url)
+	} else {
+		p.ratings[url] = n
+	}
+	p.mu.Unlock()
+	p.persistState()
+}
+
+// moveRating carries a station's rating over when its URL changes
+func (p *Player) moveRating(from, to string) {
+	p.mu.Lock()
+	n, ok := p.ratings[from]
+	if ok {
+		delete(p.ratings, from)
+		p.ratings[to] = n
+	}
+	p.mu.Unlock()
+	if ok {
+		p.persistState()
+	}
+}
+
+// savedRatings returns a copy of the ratings for the state file. Ratings
+// of stations no longer in the list are kept, in case they come back.
+func (p *Player) savedRatings() map[string]int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return maps.Clone(p.ratings)
+}
+
+// loadRatings takes the ratings from the state file, dropping any out of
+// range from a hand edit
+func (p *Player) loadRatings(saved map[string]int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ratings = maps.Clone(saved)
+	maps.DeleteFunc(p.ratings, func(_ string, n int) bool { return n < 1 || n > maxRating })
+}
+
+// sortByRatingDesc sorts station indexes highest rated first. Stations
+// with the same rating, and unrated ones at the end, keep their order.
+func sortByRatingDesc(order []int, stations []radio.Station, ratings map[string]int) {
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Compare(ratings[stations[b].URL], ratings[stations[a].URL])
+	})
+}
+
+// ratedRandomStation picks a station index other than current, like
+// randomStation, but higher-rated stations come up more often: a station
+// with 5 stars five times as often as one with 1
+func ratedRandomStation(stations []radio.Station, current int, ratings map[string]int) int {
+	if len(stations) < 2 {
+		return current
+	}
+	weights := make([]int, len(stations))
+	total := 0
+	for i, s := range stations {
+		if i == current {
+			continue
+		}
+		w := ratings[s.URL]
+		if w == 0 {
+			w = unratedWeight
+		}
+		weights[i] = w
+		total += w
+	}
+	n := rand.IntN(total)
+	for i, w := range weights {
+		if n < w {
+			return i
+		}
+		n -= w
+	}
+	return current
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/hhaidrr/cli-radio-player/pkg/radio"
+)
+
+// recentSize is how many stations the recently played list keeps
+const recentSize = 20
+
+// recentVisit is one station in the recently played list. Stations are
+// kept by URL, so the list survives the config file being reordered.
+type recentVisit struct {
+	Name string    `json:"name"`
+	URL  string    `json:"url"`
+	At   time.Time `json:"at"`
+}
+
+// recentList is a bounded back stack of the stations played, newest
+// last. Switches come from the prompt and from shuffle and skip
+// goroutines, so it has its own lock.
+type recentList struct {
+	mu     sync.Mutex
+	visits []recentVisit
+	path   string // saved here after each change when set
+}
+
+// recentPath returns the location of the saved recently played list
+func recentPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "drift-radio", "recent.json"), nil
+}
+
+// loadRecent reads the saved list from path, which it's saved back to
+// from then on. A missing file is an empty list.
+func loadRecent(path string) (*recentList, error) {
+	r := &recentList{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return r, err
+	}
+	if err := json.Unmarshal(data, &r.visits); err != nil {
+		return r, err
+	}
+	if len(r.visits) > recentSize {
+		r.visits = r.visits[len(r.visits)-recentSize:]
+	}
+	return r, nil
+}
+
+// Visit records st as played at at. Playing the newest station again
+// only updates its time. Once full, the oldest visit is dropped.
+func (r *recentList) Visit(st radio.Station, at time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if n := len(r.visits); n > 0 && r.visits[n-1].URL == st.URL {
+		r.visits[n-1].At = at
+	} else {
+		r.visits = append(r.visits, recentVisit{Name: st.Name, URL: st.URL, At: at})
+		if len(r.visits) > recentSize {
+			r.visits = r.visits[1:]
+		}
+	}
+	r.saveLocked()
+}
+
+// Back drops the newest visit and returns the one before it, like a
+// browser's back button. It's false when there's nothing to go back to.
+func (r *recentList) Back() (recentVisit, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.visits) < 2 {
+		return recentVisit{}, false
+	}
+	r.visits = r.visits[:len(r.visits)-1]
+	r.saveLocked()
+	return r.visits[len(r.visits)-1], true
+}
+
+// Visits returns the list, newest first
+func (r *recentList) Visits() []recentVisit {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	visits := make([]recentVisit, len(r.visits))
+	for i, v := range r.visits {
+		visits[len(r.visits)-1-i] = v
+	}
+	return visits
+}
+
+func (r *recentList) saveLocked() {
+	if r.path == "" {
+		return
+	}
+	data, err := json.MarshalIndent(r.visits, "", "  ")
+	if err == nil {
+		if err = os.MkdirAll(filepath.Dir(r.path), 0o755); err == nil {
+			err = os.WriteFile(r.path, data, 0o600)
+		}
+	}
+	if err != nil {
+		slog.Warn("could not save recently played stations", "err", err)
+	}
+}
+
+// stationIndex returns the position of the station with url, or -1 when
+// it's no longer in the list
+func stationIndex(stations []radio.Station, url string) int {
+	for i, s := range stations {
+		if s.URL == url {
+			return i
+		}
+	}
+	return -1
+}
+
+// printRecent lists the recently played stations, newest first, with the
+// number to switch back to each
+func printRecent(visits []recentVisit, stations []radio.Station, now time.Time) {
+	if len(visits) == 0 {
+		fmt.Println("No stations played yet")
+		return
+	}
+	fmt.Println("Recently played:")
+	for i, v := range visits {
+		num := "[-]"
+		if idx := stationIndex(stations, v.URL); idx >= 0 {
+			num = fmt.Sprintf("[%d]", idx+1)
+		}
+		mark := ""
+		if i == 0 {
+			mark = "  (now)"
+		}
+		fmt.Printf("  %-5s %s  %s%s\n", num, v.Name, visitTime(v.At, now), mark)
+	}
+}
+
+// visitTime formats when a station was played: the time of day for today,
+// with the date for earlier days
+func visitTime(at, now time.Time) string {
+	at = at.Local()
+	y, m, d := now.Local().Date()
+	if ay, am, ad := at.Date(); ay == y && am == m && ad == d {
+		return at.Format("15:04")
+	}
+	return at.Format("Jan 2 15:04")
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hhaidrr/cli-radio-player/pkg/radio"
+)
+
+// stationsFetchTimeout bounds the download of a -stations-url list
+const stationsFetchTimeout = 15 * time.Second
+
+// maxStationsSize caps how much of a -stations-url response is read
+const maxStationsSize = 4 << 20
+
+// stationsCachePath returns where the last good copy of the list at url
+// is kept. Each URL gets its own file, so switching lists doesn't mix them.
+func stationsCachePath(url string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(dir, "drift-radio", fmt.Sprintf("stations-%x.json", sum[:6])), nil
+}
+
+// fetchStations downloads the station list at url, in the config file's
+// format, and validates it like a config file. Only the stations are
+// used. A list that passes is cached for cachedStations.
+func fetchStations(client *http.Client, url string) ([]radio.Station, []string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), stationsFetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("server answered %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxStationsSize+1))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(data) > maxStationsSize {
+		return nil, nil, fmt.Errorf("station list is larger than %s", radio.FormatBytes(maxStationsSize))
+	}
+	cfg, warnings, err := parseConfig(data, radio.RedactURL(url))
+	if err != nil {
+		return nil, warnings, err
+	}
+	if err := saveStationsCache(url, data); err != nil {
+		slog.Warn("could not cache the station list", "err", err)
+	}
+	return cfg.Stations, warnings, nil
+}
+
+// saveStationsCache keeps data as the last good copy of the list at url
+func saveStationsCache(url string, data []byte) error {
+	path, err := stationsCachePath(url)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// cachedStations reads the copy of the list at url saved by the last
+// successful fetchStations
+func cachedStations(url string) ([]radio.Station, []string, error) {
+	path, err := stationsCachePath(url)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, errors.New("no cached copy to fall back on")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg, warnings, err := parseConfig(data, path)
+	return cfg.Stations, warnings, err
+}
+
+// mergeStations adds the remote stations to the local ones, skipping any
+// whose URL is already there. Without merge the remote list replaces them.
+func mergeStations(local, remote []radio.Station, merge bool) []radio.Station {
+	if !merge {
+		return remote
+	}
+	seen := make(map[string]bool, len(local))
+	for _, s := range local {
+		seen[s.URL] = true
+	}
+	merged := append([]radio.Station(nil), local...)
+	for _, s := range remote {
+		if !seen[s.URL] {
+			merged = append(merged, s)
+		}
+	}
+	return merged
+}
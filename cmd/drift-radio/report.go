@@ -0,0 +1,147 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hhaidrr/cli-radio-player/pkg/radio"
+)
+
+// reportTime is how times are written in the export report
+const reportTime = "2006-01-02 15:04:05"
+
+// commandLineFlags returns the flags given on the command line as -name=value,
+// in name order, with passwords in URLs masked, for the export report
+func commandLineFlags() []string {
+	var flags []string
+	flag.Visit(func(f *flag.Flag) {
+		flags = append(flags, "-"+f.Name+"="+radio.RedactURL(f.Value.String()))
+	})
+	return flags
+}
+
+// exportReport handles the export command: the session so far, written
+// to path as Markdown for attaching to a bug report. Sections and lines
+// come in a fixed order so reports from two sessions can be diffed.
+func (p *Player) exportReport(path string, now time.Time) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	p.writeReport(f, now)
+	return f.Close()
+}
+
+// writeReport writes the export report to w
+func (p *Player) writeReport(w io.Writer, now time.Time) {
+	fmt.Fprintln(w, "# drift-radio session report")
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "- Version: %s\n", versionText())
+	fmt.Fprintf(w, "- Exported: %s\n", now.Format(reportTime))
+	fmt.Fprintf(w, "- Session: %s, started %s\n", formatElapsed(now.Sub(p.sessionStart)), p.sessionStart.Format(reportTime))
+	p.session.writeReport(w, now)
+
+	st := p.Status()
+	eq := st.EQ
+	if eq == "" {
+		eq = "flat"
+	}
+	device := st.Device
+	if device == "" {
+		device = "(system default)"
+	}
+	configFile := p.configPath
+	if configFile == "" {
+		configFile = "(none; built-in stations)"
+	}
+	flags := "(none)"
+	if len(p.flags) > 0 {
+		flags = "`" + strings.Join(p.flags, " ") + "`"
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "## Config")
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "- Config file: %s\n", configFile)
+	fmt.Fprintf(w, "- Flags: %s\n", flags)
+	fmt.Fprintf(w, "- Backend: %s\n", st.Backend)
+	fmt.Fprintf(w, "- Device: %s\n", device)
+	fmt.Fprintf(w, "- Volume: %s\n", volumeText(st.Volume))
+	fmt.Fprintf(w, "- EQ: %s\n", eq)
+	mono := "off"
+	if st.Mono {
+		mono = "on"
+	}
+	fmt.Fprintf(w, "- Mono: %s\n", mono)
+	fmt.Fprintf(w, "- Quality: %s\n", st.Quality)
+}
+
+// writeReport writes the session log's part of the export report: the
+// totals, the timeline of what played, and the quality alerts. A station
+// still playing is in the timeline up to now. It names the stations even
+// with -blind, as the summary does.
+func (s *sessionLog) writeReport(w io.Writer, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	timeline := s.timeline
+	var listened time.Duration
+	for _, span := range timeline {
+		listened += span.end.Sub(span.start)
+	}
+	if s.current != "" {
+		listened += now.Sub(s.span.start)
+		timeline = append(timeline[:len(timeline):len(timeline)], s.span)
+	}
+	fmt.Fprintf(w, "- Listened: %s\n", formatElapsed(listened))
+	fmt.Fprintf(w, "- Download speed: %s\n", s.speedTextLocked())
+	fmt.Fprintf(w, "- Data used: %s\n", s.dataTextLocked())
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "## Timeline")
+	fmt.Fprintln(w)
+	if len(timeline) == 0 {
+		fmt.Fprintln(w, "Nothing played.")
+	} else {
+		fmt.Fprintln(w, "| Start | End | Station | Played | Codec | Bitrate | Download speed | Network |")
+		fmt.Fprintln(w, "|---|---|---|---|---|---|---|---|")
+		for _, span := range timeline {
+			end, played := "(playing)", now.Sub(span.start)
+			if !span.end.IsZero() {
+				end, played = span.end.Format(reportTime), span.end.Sub(span.start)
+			}
+			codec, bitrate, speed, network := "N/A", "N/A", "N/A", "N/A"
+			if span.codec != "" {
+				codec = span.codec
+			}
+			if span.bitrate > 0 {
+				bitrate = fmt.Sprintf("%d kbps", span.bitrate/1000)
+			}
+			if span.speeds > 0 {
+				speed = radio.FormatBytes(int64(span.speedSum/float64(span.speeds))) + "/s"
+			}
+			if span.quality != "" {
+				network = span.quality
+			}
+			fmt.Fprintf(w, "| %s | %s | %s | %s | %s | %s | %s | %s |\n",
+				span.start.Format(reportTime), end, markdownCell(span.name), formatElapsed(played), codec, bitrate, speed, network)
+		}
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "## Quality alerts")
+	fmt.Fprintln(w)
+	if len(s.alerts) == 0 {
+		fmt.Fprintln(w, "None.")
+	}
+	for _, a := range s.alerts {
+		fmt.Fprintf(w, "- %s (%s)\n", a.kind, a.timesText())
+	}
+}
+
+// markdownCell escapes s for a Markdown table cell
+func markdownCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
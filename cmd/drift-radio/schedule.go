@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ScheduleRule plays a station from a time of day on, in the config
+// file's schedule list
+type ScheduleRule struct {
+	After   string `json:"after"`   // "HH:MM", 24-hour
+	Station int    `json:"station"` // station number, from 1
+}
+
+// scheduleRule is a checked ScheduleRule
+type scheduleRule struct {
+	minute  int // minutes after midnight
+	station int // index into the station list
+}
+
+// parseSchedule checks the config file's schedule against the station
+// list and sorts it by time. Rules with problems are skipped and
+// described in the warnings.
+func parseSchedule(rules []ScheduleRule, count int) ([]scheduleRule, []string) {
+	var (
+		parsed   []scheduleRule
+		warnings []string
+		seen     = map[int]bool{}
+	)
+	for i, r := range rules {
+		label := fmt.Sprintf("schedule rule %d", i+1)
+		t, err := time.Parse("15:04", strings.TrimSpace(r.After))
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("%s: invalid time %q (use HH:MM, e.g. 18:30); skipped", label, r.After))
+			continue
+		}
+		if r.Station < 1 || r.Station > count {
+			warnings = append(warnings, fmt.Sprintf("%s: there's no station %d; skipped", label, r.Station))
+			continue
+		}
+		minute := t.Hour()*60 + t.Minute()
+		if seen[minute] {
+			warnings = append(warnings, fmt.Sprintf("%s: another rule already starts at %s; skipped", label, t.Format("15:04")))
+			continue
+		}
+		seen[minute] = true
+		parsed = append(parsed, scheduleRule{minute: minute, station: r.Station - 1})
+	}
+	sort.Slice(parsed, func(i, j int) bool { return parsed[i].minute < parsed[j].minute })
+	return parsed, warnings
+}
+
+// activeRule returns the rule in effect at now: the last one to have
+// started today, or before the first one, the last of yesterday's
+func activeRule(rules []scheduleRule, now time.Time) scheduleRule {
+	minute := now.Hour()*60 + now.Minute()
+	active := rules[len(rules)-1]
+	for _, r := range rules {
+		if r.minute <= minute {
+			active = r
+		}
+	}
+	return active
+}
+
+// nextScheduleChange returns when the next rule after now starts. When
+// that's after midnight it returns midnight instead, with ok false, so the
+// times are worked out afresh for the new day, e.g. across a daylight
+// saving change.
+func nextScheduleChange(rules []scheduleRule, now time.Time) (at time.Time, rule scheduleRule, ok bool) {
+	midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+	for day := 0; day < 2; day++ {
+		for _, r := range rules {
+			at := time.Date(now.Year(), now.Month(), now.Day()+day, r.minute/60, r.minute%60, 0, 0, now.Location())
+			if !at.After(now) {
+				continue
+			}
+			if at.After(midnight) {
+				return midnight, scheduleRule{}, false
+			}
+			return at, r, true
+		}
+	}
+	return midnight, scheduleRule{}, false
+}
+
+// runSchedule calls play with each rule's station as its time comes,
+// until ctx is cancelled. A station picked by hand in between plays until
+// the next rule starts.
+func (p *Player) runSchedule(ctx context.Context, play func(idx int)) {
+	for {
+		at, rule, ok := nextScheduleChange(p.schedule, time.Now())
+		if !waitUntil(ctx, at) {
+			return
+		}
+		if ok {
+			play(rule.station)
+		}
+	}
+}
+
+// describeSchedule says which station the schedule starts on and until when
+func describeSchedule(rules []scheduleRule, name string, now time.Time) string {
+	if len(rules) == 1 {
+		return fmt.Sprintf("%s all day (one schedule rule)", name)
+	}
+	at, _, ok := nextScheduleChange(rules, now)
+	for !ok {
+		at, _, ok = nextScheduleChange(rules, at)
+	}
+	return fmt.Sprintf("%s until %s", name, at.Format("15:04"))
+}
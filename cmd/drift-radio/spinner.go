@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// spinnerFrames animate the loading line
+var spinnerFrames = []rune("⠋⠙⠹⠸⠼⠴⠦⠧⠇⠏")
+
+const (
+	spinnerInterval = 100 * time.Millisecond
+	// maxSpinnerWait gives the prompt back when a stream that's still
+	// trying hasn't connected after this long
+	maxSpinnerWait = 30 * time.Second
+)
+
+// spinner animates a message on the current line until stopped
+type spinner struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+// startSpinner shows label behind a spinning mark. With plain output
+// there's no animation; the label is printed once.
+func startSpinner(label string) *spinner {
+	s := &spinner{stop: make(chan struct{}), done: make(chan struct{})}
+	if plainOutput {
+		uiPrintln("⏳ " + label)
+		close(s.done)
+		return s
+	}
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(spinnerInterval)
+		defer ticker.Stop()
+		for i := 0; ; i++ {
+			fmt.Printf("\r%c %s\033[K", spinnerFrames[i%len(spinnerFrames)], label)
+			select {
+			case <-s.stop:
+				fmt.Print("\r\033[K")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return s
+}
+
+// Stop clears the line and waits for the animation to finish
+func (s *spinner) Stop() {
+	close(s.stop)
+	<-s.done
+}
+
+// startWithSpinner runs start, such as a Start or Restart, behind an
+// animated "Loading stream..." line that lasts until the stream's server
+// answers, start fails, or playback stops. The full stats display holds
+// off meanwhile so it doesn't draw over the line.
+func (p *Player) startWithSpinner(start func() error) error {
+	p.mu.Lock()
+	p.loading = true
+	p.mu.Unlock()
+	sp := startSpinner("Loading stream...")
+	defer func() {
+		sp.Stop()
+		p.mu.Lock()
+		p.loading = false
+		p.mu.Unlock()
+	}()
+	if err := start(); err != nil {
+		return err
+	}
+
+	connected := p.Connected()
+	deadline := time.After(maxSpinnerWait)
+	ticker := time.NewTicker(spinnerInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-connected:
+			return nil
+		case <-deadline:
+			return nil
+		case <-ticker.C:
+			if !p.Playing() {
+				return nil
+			}
+			// A failover to a mirror watches a new connection
+			connected = p.Connected()
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// State holds the player settings remembered between runs
+type State struct {
+	Station int    `json:"station"`
+	Volume  int    `json:"volume"`
+	EQ      string `json:"eq,omitempty"`
+	Quality string `json:"quality,omitempty"`
+	Device  string `json:"device,omitempty"`
+	Mono    bool   `json:"mono,omitempty"`
+
+	// Stars from 1 to 5 by station URL, so they follow a station that
+	// moves in the list
+	Ratings map[string]int `json:"ratings,omitempty"`
+
+	// The favorites shuffle's interval, e.g. "10m0s", while it's on
+	ShuffleFavs string `json:"shuffle_favs,omitempty"`
+}
+
+// statePath returns the location of the state file
+func statePath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "drift-radio", "state.json"), nil
+}
+
+// loadState reads the saved state. The boolean is false when no state
+// has been saved yet.
+func loadState() (State, bool, error) {
+	var st State
+	path, err := statePath()
+	if err != nil {
+		return st, false, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return st, false, nil
+	}
+	if err != nil {
+		return st, false, err
+	}
+	if err := json.Unmarshal(data, &st); err != nil {
+		return st, false, err
+	}
+	return st, true, nil
+}
+
+// saveState writes the state file, creating its directory if needed
+func saveState(st State) error {
+	path, err := statePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hhaidrr/cli-radio-player/pkg/radio"
+)
+
+// The add, edit, and remove commands change the config file in place:
+// only the station they touch is rewritten, so the rest of the file keeps
+// its fields, order, and formatting.
+
+// jsonSpan is where a value sits in a JSON document, with its key when
+// it's an object member
+type jsonSpan struct {
+	key        string
+	start, end int
+}
+
+// jsonMembers returns the spans of the members of the object or array in
+// data[start:end]
+func jsonMembers(data []byte, start, end int) ([]jsonSpan, error) {
+	dec := json.NewDecoder(bytes.NewReader(data[start:end]))
+	open, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	isObject := open == json.Delim('{')
+	if !isObject && open != json.Delim('[') {
+		return nil, errors.New("not a JSON object or array")
+	}
+	var spans []jsonSpan
+	for dec.More() {
+		var key string
+		if isObject {
+			t, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, _ = t.(string)
+		}
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+		valueEnd := start + int(dec.InputOffset())
+		spans = append(spans, jsonSpan{key: key, start: valueEnd - len(raw), end: valueEnd})
+	}
+	return spans, nil
+}
+
+// marshalEntry encodes v for the config file, indented to follow prefix.
+// URLs keep their & rather than becoming \u0026.
+func marshalEntry(v any, prefix string) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent(prefix, "  ")
+	err := enc.Encode(v)
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), err
+}
+
+// splice returns data with data[start:end] replaced by insert
+func splice(data []byte, start, end int, insert []byte) []byte {
+	return slices.Concat(data[:start], insert, data[end:])
+}
+
+// lineIndent returns the whitespace that starts the line holding data[pos]
+func lineIndent(data []byte, pos int) []byte {
+	lineStart := bytes.LastIndexByte(data[:pos], '\n') + 1
+	lineEnd := lineStart
+	for lineEnd < pos && (data[lineEnd] == ' ' || data[lineEnd] == '\t') {
+		lineEnd++
+	}
+	return data[lineStart:lineEnd]
+}
+
+// configStations finds the stations array in a config file and the
+// stations in it
+func configStations(data []byte) (jsonSpan, []jsonSpan, error) {
+	top, err := jsonMembers(data, 0, len(data))
+	if err != nil {
+		return jsonSpan{}, nil, err
+	}
+	for _, m := range top {
+		if m.key != "stations" {
+			continue
+		}
+		items, err := jsonMembers(data, m.start, m.end)
+		return m, items, err
+	}
+	return jsonSpan{}, nil, errors.New("no stations list")
+}
+
+// findConfigStation returns the index among items of the station whose URL
+// is url once normalized, as it is in the station list
+func findConfigStation(data []byte, items []jsonSpan, url string) int {
+	for i, item := range items {
+		var s radio.Station
+		if json.Unmarshal(data[item.start:item.end], &s) != nil {
+			continue
+		}
+		if u, _, err := radio.NormalizeStationURL(s.URL); err == nil && u == url {
+			return i
+		}
+	}
+	return -1
+}
+
+// readConfigForEdit reads the config file. Without one, it starts from
+// the built-in stations, which are the ones playing.
+func readConfigForEdit(path string) ([]byte, os.FileMode, error) {
+	if path == "" {
+		return nil, 0, errors.New("there's no config file location")
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		data, err = marshalEntry(map[string]any{"stations": defaultStations}, "")
+		return append(data, '\n'), 0o600, err
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, info.Mode().Perm(), nil
+}
+
+// writeEditedConfig saves an edited config file, once it's certain to
+// load again
+func writeEditedConfig(path string, data []byte, mode os.FileMode) error {
+	if _, _, err := parseConfig(data, path); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, mode)
+}
+
+// addConfigStation appends st to the config file's stations
+func addConfigStation(path string, st radio.Station) error {
+	data, mode, err := readConfigForEdit(path)
+	if err != nil {
+		return err
+	}
+	list, items, err := configStations(data)
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		entry, err := marshalEntry([]radio.Station{st}, string(lineIndent(data, list.start)))
+		if err != nil {
+			return err
+		}
+		return writeEditedConfig(path, splice(data, list.start, list.end, entry), mode)
+	}
+	// Follow the layout of the stations already there: one per line and
+	// indented, or all on one line
+	last := items[len(items)-1]
+	indent := lineIndent(data, last.start)
+	entry, err := marshalEntry(st, string(indent))
+	if err != nil {
+		return err
+	}
+	sep := slices.Concat([]byte(",\n"), indent)
+	if !bytes.Contains(data[list.start:items[0].start], []byte("\n")) {
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, entry); err != nil {
+			return err
+		}
+		sep, entry = []byte(", "), compact.Bytes()
+	}
+	return writeEditedConfig(path, splice(data, last.end, last.end, slices.Concat(sep, entry)), mode)
+}
+
+// removeConfigStation deletes the station with url from the config file
+func removeConfigStation(path, url string) error {
+	data, mode, err := readConfigForEdit(path)
+	if err != nil {
+		return err
+	}
+	_, items, err := configStations(data)
+	if err != nil {
+		return err
+	}
+	i := findConfigStation(data, items, url)
+	switch {
+	case i < 0:
+		return errNotInConfig
+	case len(items) == 1:
+		data = splice(data, items[0].start, items[0].end, nil)
+	case i == 0:
+		// Take the separator after it along
+		data = splice(data, items[0].start, items[1].start, nil)
+	default:
+		data = splice(data, items[i-1].end, items[i].end, nil)
+	}
+	return writeEditedConfig(path, data, mode)
+}
+
+// updateConfigStation changes the station old in the config file to st.
+// Only the name, URL, and description fields that differ are rewritten;
+// the station's other settings are kept as written.
+func updateConfigStation(path string, old, st radio.Station) error {
+	data, mode, err := readConfigForEdit(path)
+	if err != nil {
+		return err
+	}
+	_, items, err := configStations(data)
+	if err != nil {
+		return err
+	}
+	i := findConfigStation(data, items, old.URL)
+	if i < 0 {
+		return errNotInConfig
+	}
+	entry := data[items[i].start:items[i].end]
+	fields := []struct {
+		key, old, value string
+	}{{"name", old.Name, st.Name}, {"url", old.URL, st.URL}, {"description", old.Description, st.Description}}
+	for _, f := range fields {
+		if f.value == f.old {
+			continue
+		}
+		if entry, err = setJSONField(entry, f.key, f.value); err != nil {
+			return err
+		}
+	}
+	return writeEditedConfig(path, splice(data, items[i].start, items[i].end, entry), mode)
+}
+
+// errNotInConfig means a station came from somewhere other than the config
+// file, such as -stations-url, so the editor can't change it
+var errNotInConfig = errors.New("it isn't in the config file (it may have come from -stations-url)")
+
+// setJSONField returns the object obj with key set to the string value,
+// added after its last field when it's missing. An empty value removes
+// the field instead, unless it's the first one.
+func setJSONField(obj []byte, key, value string) ([]byte, error) {
+	members, err := jsonMembers(obj, 0, len(obj))
+	if err != nil {
+		return nil, err
+	}
+	quoted, err := marshalEntry(value, "")
+	if err != nil {
+		return nil, err
+	}
+	for i, m := range members {
+		switch {
+		case m.key != key:
+		case value == "" && i > 0:
+			// Along with the separator before it
+			return splice(obj, members[i-1].end, m.end, nil), nil
+		default:
+			return splice(obj, m.start, m.end, quoted), nil
+		}
+	}
+	if value == "" {
+		return obj, nil
+	}
+	field := fmt.Appendf(nil, "%s: %s", strconv.Quote(key), quoted)
+	if len(members) == 0 {
+		return splice(obj, 1, 1, field), nil
+	}
+	last := members[len(members)-1]
+	sep := []byte(", ")
+	if first := members[0].start; bytes.Contains(obj[:first], []byte("\n")) {
+		sep = slices.Concat([]byte(",\n"), lineIndent(obj, first))
+	}
+	return splice(obj, last.end, last.end, slices.Concat(sep, field)), nil
+}
+
+// newStation checks the station the add and edit commands were given,
+// like a station in the config file
+func newStation(name, url, description string) (radio.Station, error) {
+	u, warnings, err := radio.NormalizeStationURL(url)
+	if err != nil {
+		return radio.Station{}, err
+	}
+	for _, w := range warnings {
+		fmt.Println("Warning:", w)
+	}
+	st := radio.Station{Name: strings.TrimSpace(name), URL: u, Description: strings.TrimSpace(description)}
+	if st.Name == "" {
+		st.Name = st.URL
+	}
+	return st, nil
+}
+
+// stationNumber parses the N of "edit N" and "remove N" into an index
+func stationNumber(arg string, count int) (int, bool) {
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 1 || n > count {
+		return 0, false
+	}
+	return n - 1, true
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+)
+
+// statusLine keeps a one-line player summary on the terminal's bottom row.
+// A scroll region stops other output from running over it, and it's drawn
+// with the cursor saved and restored so a command being typed isn't
+// disturbed.
+type statusLine struct {
+	mu      sync.Mutex
+	rows    int
+	cols    int
+	text    string
+	resize  chan os.Signal
+	resized chan struct{}
+}
+
+// newStatusLine reserves the bottom row of the terminal. It returns nil
+// for plain output or when the terminal size is unknown, and callers then
+// print changes as ordinary lines.
+func newStatusLine() *statusLine {
+	if plainOutput {
+		return nil
+	}
+	rows, cols, err := terminalSize(os.Stdout.Fd())
+	if err != nil || rows < 3 || cols < 20 {
+		return nil
+	}
+	s := &statusLine{
+		rows:    rows,
+		cols:    cols,
+		resize:  make(chan os.Signal, 1),
+		resized: make(chan struct{}, 1),
+	}
+	// Scroll once so the cursor isn't left on the reserved row
+	fmt.Print("\n\033[1A")
+	s.reserveLocked()
+	notifyResize(s.resize)
+	go func() {
+		for range s.resize {
+			rows, cols, err := terminalSize(os.Stdout.Fd())
+			if err != nil {
+				continue
+			}
+			s.mu.Lock()
+			if rows > s.rows {
+				// The old bottom row is inside the scroll region now
+				fmt.Printf("\0337\033[%d;1H\033[2K\0338", s.rows)
+			}
+			s.rows, s.cols = rows, cols
+			s.reserveLocked()
+			s.drawLocked()
+			s.mu.Unlock()
+			select {
+			case s.resized <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return s
+}
+
+// reserveLocked limits scrolling to the rows above the status line.
+// Setting the region homes the cursor, so it's saved around it.
+func (s *statusLine) reserveLocked() {
+	fmt.Printf("\0337\033[1;%dr\0338", s.rows-1)
+}
+
+func (s *statusLine) drawLocked() {
+	// Keep it to one row: a wrapped line would scroll the whole screen
+	text := fitWidth(s.text, s.cols)
+	fmt.Printf("\0337\033[%d;1H\033[2K%s\0338", s.rows, text)
+}
+
+// Set replaces the status text and draws it
+func (s *statusLine) Set(text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.text = text
+	s.drawLocked()
+}
+
+// Redraw draws the status line again after the screen was cleared
+func (s *statusLine) Redraw() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.drawLocked()
+}
+
+// Resized signals after the terminal changes size and the status line
+// has been redrawn to fit
+func (s *statusLine) Resized() <-chan struct{} {
+	return s.resized
+}
+
+// Close gives the bottom row back to the terminal
+func (s *statusLine) Close() {
+	signal.Stop(s.resize)
+	close(s.resize)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Printf("\0337\033[r\033[%d;1H\033[2K\0338", s.rows)
+}
+
+// volumeBar draws a 10-cell bar, e.g. [██████----] for 60%
+func volumeBar(percent int) string {
+	filled := max(0, min(10, (percent+5)/10))
+	return "[" + strings.Repeat("█", filled) + strings.Repeat("-", 10-filled) + "]"
+}
+
+// statusText summarizes the player for the status line, with the track
+// title (already fitted by a marquee) when there is one
+func statusText(volume int, playing bool, station, title string) string {
+	icon, boost := "\U0001F50A", ""
+	switch {
+	case volume == 0:
+		icon = "\U0001F507"
+	case volume > 100:
+		icon, boost = "\U0001F4E2", " BOOST"
+	}
+	state := "⏹ Stopped"
+	if playing {
+		state = "▶ Playing"
+	}
+	text := fmt.Sprintf("%s %s %3d%%%s  %s: %s", icon, volumeBar(volume), volume, boost, state, station)
+	if title != "" {
+		text += "  ♪ " + title
+	}
+	return text
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/hhaidrr/cli-radio-player/pkg/radio"
+)
+
+// parseStationList reads the -stdin station list: one station per line,
+// either "Name|URL" or a bare URL, which is its own name. Blank lines and
+// lines starting with # are skipped. Lines with a bad URL are skipped and
+// described in the warnings; the error is only set when nothing usable
+// is left or r can't be read.
+func parseStationList(r io.Reader) ([]radio.Station, []string, error) {
+	var stations []radio.Station
+	var warnings []string
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, u, ok := strings.Cut(line, "|")
+		if !ok {
+			name, u = "", line
+		}
+		name, u = strings.TrimSpace(name), strings.TrimSpace(u)
+		normalized, urlWarnings, err := radio.NormalizeStationURL(u)
+		for _, w := range urlWarnings {
+			warnings = append(warnings, fmt.Sprintf("line %d: %s", n, w))
+		}
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("line %d: %v; skipped", n, err))
+			continue
+		}
+		if name == "" {
+			name = normalized
+		}
+		stations = append(stations, radio.Station{Name: name, URL: normalized})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, warnings, fmt.Errorf("read stdin: %w", err)
+	}
+	if len(stations) == 0 {
+		return nil, warnings, fmt.Errorf("stdin has no usable stations")
+	}
+	return stations, warnings, nil
+}
+
+// ttyPath is the terminal the interactive commands are read from once
+// stdin has been used up by -stdin
+func ttyPath() string {
+	if runtime.GOOS == "windows" {
+		return "CONIN$"
+	}
+	return "/dev/tty"
+}
+
+// readStdinStations reads -stdin's station list and then points os.Stdin
+// at the terminal, so the interactive loop reads commands from the
+// keyboard rather than the spent pipe. It reports false for interactive
+// when there's no terminal to read from, such as under cron.
+func readStdinStations() (stations []radio.Station, warnings []string, interactive bool, err error) {
+	stations, warnings, err = parseStationList(os.Stdin)
+	if err != nil {
+		return nil, warnings, false, err
+	}
+	tty, err := os.Open(ttyPath())
+	if err != nil {
+		return stations, warnings, false, nil
+	}
+	os.Stdin = tty
+	return stations, warnings, true, nil
+}
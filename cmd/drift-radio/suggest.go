@@ -0,0 +1,40 @@
+package main
+
+// suggestCommand returns the command closest to input by edit distance,
+// if it's close enough to be a plausible typo
+func suggestCommand(input string, commands []string) (string, bool) {
+	best, bestDist := "", -1
+	for _, c := range commands {
+		d := levenshtein(input, c)
+		if bestDist < 0 || d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	// Two edits at most, and never a full rewrite of a short word
+	if bestDist < 1 || bestDist > 2 || bestDist >= len([]rune(input)) {
+		return "", false
+	}
+	return best, true
+}
+
+// levenshtein returns the edit distance between a and b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hhaidrr/cli-radio-player/pkg/radio"
+)
+
+// sessionLog gathers what the session played, for the summary printed on
+// quit. The engine's changes and the stats loop feed it from their own
+// goroutines, hence the lock.
+type sessionLog struct {
+	mu       sync.Mutex
+	stations []stationTime // in the order they were first played
+	current  string        // station playing since since; "" when none
+	since    time.Time
+	span     playSpan   // the current station's stretch, while current is set
+	timeline []playSpan // finished stretches, in order, for the export
+
+	speedSum   float64 // download speed samples, in bytes/sec
+	speeds     int
+	peakSpeed  float64
+	data       int64 // bytes downloaded this session, when measured
+	measured   bool
+	alerts     []alertCount    // by first occurrence
+	lastAlerts map[string]bool // kinds raised at the last sample
+}
+
+// stationTime is how long a station played this session
+type stationTime struct {
+	name   string
+	played time.Duration
+}
+
+// playSpan is one stretch of a station playing, with what the stats
+// said about it
+type playSpan struct {
+	name       string
+	start, end time.Time
+	codec      string
+	bitrate    int64   // bits per second; 0 when unknown
+	speedSum   float64 // download speed samples, in bytes/sec
+	speeds     int
+	quality    string // network quality at the last sample
+}
+
+// alertCount is how many times a kind of quality alert was raised
+type alertCount struct {
+	kind  string
+	times int
+}
+
+// played records a player change: name started playing when on, or
+// whatever played stopped otherwise
+func (s *sessionLog) played(name string, on bool, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if on && name == s.current {
+		// A volume change or the like; the same stretch goes on
+		return
+	}
+	s.closeLocked(now)
+	if on {
+		s.current, s.since = name, now
+		s.span = playSpan{name: name, start: now}
+	}
+}
+
+// closeLocked adds the time the current station has played up to now to
+// its total. Callers hold s.mu.
+func (s *sessionLog) closeLocked(now time.Time) {
+	if s.current == "" {
+		return
+	}
+	name, d := s.current, now.Sub(s.since)
+	s.current = ""
+	s.span.end = now
+	s.timeline = append(s.timeline, s.span)
+	for i := range s.stations {
+		if s.stations[i].name == name {
+			s.stations[i].played += d
+			return
+		}
+	}
+	s.stations = append(s.stations, stationTime{name, d})
+}
+
+// sample records a stats update taken while a stream played. An alert
+// that stays up counts once until it clears.
+func (s *sessionLog) sample(stats radio.StreamStats, alerts []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.speedSum += stats.DownloadSpeed
+	s.speeds++
+	s.peakSpeed = max(s.peakSpeed, stats.DownloadSpeed)
+	s.data, s.measured = stats.SessionBytes, stats.DataMeasured
+	if s.current != "" {
+		s.span.speedSum += stats.DownloadSpeed
+		s.span.speeds++
+		if stats.Codec != "" {
+			s.span.codec = stats.Codec
+		}
+		if stats.Bitrate > 0 {
+			s.span.bitrate = stats.Bitrate
+		}
+		s.span.quality = stats.NetworkQuality
+	}
+	raised := make(map[string]bool, len(alerts))
+	for _, a := range alerts {
+		kind := alertKind(a)
+		raised[kind] = true
+		if s.lastAlerts[kind] {
+			continue
+		}
+		found := false
+		for i := range s.alerts {
+			if s.alerts[i].kind == kind {
+				s.alerts[i].times++
+				found = true
+				break
+			}
+		}
+		if !found {
+			s.alerts = append(s.alerts, alertCount{kind, 1})
+		}
+	}
+	s.lastAlerts = raised
+}
+
+// alertKind drops the readings from a quality alert, e.g. "Low buffer
+// health" for "Low buffer health: 12.0% - Stream may stutter"
+func alertKind(alert string) string {
+	if kind, _, ok := strings.Cut(alert, ":"); ok {
+		return kind
+	}
+	kind, _, _ := strings.Cut(alert, " - ")
+	return kind
+}
+
+// printSummary prints the session's summary, unless nothing played. It
+// names the stations even with -blind: the session is over.
+func (s *sessionLog) printSummary(session time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeLocked(time.Now())
+	if len(s.stations) == 0 {
+		return
+	}
+	var listened time.Duration
+	width := 0
+	for _, st := range s.stations {
+		listened += st.played
+		width = max(width, len([]rune(st.name)))
+	}
+	uiPrintln("\n📊 Session Summary:")
+	uiPrintf("├─ Listened: %s of a %s session\n", formatElapsed(listened), formatElapsed(session))
+	uiPrintln("├─ Stations:")
+	for _, st := range s.stations {
+		uiPrintf("│    %-*s  %s\n", width, st.name, formatElapsed(st.played))
+	}
+	uiPrintf("├─ Download Speed: %s\n", s.speedTextLocked())
+	uiPrintf("├─ Data Used: %s\n", s.dataTextLocked())
+	if len(s.alerts) == 0 {
+		uiPrintln("└─ Quality Alerts: none")
+		return
+	}
+	uiPrintln("└─ Quality Alerts:")
+	for _, a := range s.alerts {
+		uiPrintf("     • %s (%s)\n", a.kind, a.timesText())
+	}
+}
+
+// speedTextLocked describes the session's download speeds. Callers hold
+// s.mu.
+func (s *sessionLog) speedTextLocked() string {
+	if s.speeds == 0 {
+		return "N/A"
+	}
+	return fmt.Sprintf("%s/s average, %s/s peak", radio.FormatBytes(int64(s.speedSum/float64(s.speeds))), radio.FormatBytes(int64(s.peakSpeed)))
+}
+
+// dataTextLocked describes the data the session used. Callers hold s.mu.
+func (s *sessionLog) dataTextLocked() string {
+	if !s.measured {
+		return "N/A"
+	}
+	return radio.FormatBytes(s.data)
+}
+
+// timesText says how often the alert was raised, e.g. "once"
+func (a alertCount) timesText() string {
+	if a.times == 1 {
+		return "once"
+	}
+	return fmt.Sprintf("%d times", a.times)
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// plainOutput is set when stdout isn't a terminal or color is turned off.
+// UI text is then printed as plain ASCII without emoji or escape codes so
+// logs and captured CI output stay clean.
+var plainOutput bool
+
+// detectPlainOutput decides whether to print plain output: when asked to
+// with --no-color or NO_COLOR, on a dumb terminal, or when stdout isn't a TTY
+func detectPlainOutput(noColor bool) bool {
+	if noColor || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return true
+	}
+	fi, err := os.Stdout.Stat()
+	if err != nil {
+		return true
+	}
+	return fi.Mode()&os.ModeCharDevice == 0
+}
+
+var ansiRegexp = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+
+// asciiReplacer maps the UI's emoji and box-drawing characters to ASCII
+var asciiReplacer = strings.NewReplacer(
+	"⚠️  ", "! ",
+	"⚠️", "!",
+	"•", "-",
+	"★", "*",
+	"☆", ".",
+	"✓", "OK",
+	"├─", "|-",
+	"└─", "`-",
+	"│", "|",
+	"\U0001F50A ", "",
+	"\U0001F509 ", "",
+	"\U0001F3B5 ", "",
+	"⏳ ", "",
+	"\U0001F4AA ", "",
+	"📊 ", "",
+	"⏱️  ", "",
+	"⏰ ", "",
+	"📉 ", "",
+	"📈 ", "",
+	"📋 ", "",
+	"🌐 ", "",
+	"🕒 ", "",
+	"⬇", "dl:",
+)
+
+// qualityColor returns the ANSI color for a network quality rating:
+// green when it's good, yellow when fair, red when poor, and none when
+// there's no rating yet
+func qualityColor(quality string) string {
+	switch quality {
+	case "Excellent", "Good":
+		return "\033[32m"
+	case "Fair":
+		return "\033[33m"
+	case "Poor", "Very Poor":
+		return "\033[31m"
+	}
+	return ""
+}
+
+// promptText returns the radio> prompt, colored by network quality
+func promptText(quality string) string {
+	color := qualityColor(quality)
+	if plainOutput || color == "" {
+		return "radio> "
+	}
+	return color + "radio>\033[0m "
+}
+
+// plainText strips ANSI escape codes and swaps emoji for ASCII
+func plainText(s string) string {
+	return asciiReplacer.Replace(ansiRegexp.ReplaceAllString(s, ""))
+}
+
+// uiPrintf prints UI text, made plain when plainOutput is set
+func uiPrintf(format string, a ...any) {
+	s := fmt.Sprintf(format, a...)
+	if plainOutput {
+		s = plainText(s)
+	}
+	fmt.Print(s)
+}
+
+// uiPrintln is uiPrintf with Println formatting
+func uiPrintln(a ...any) {
+	uiPrintf("%s", fmt.Sprintln(a...))
+}
+
+// terminalCols returns stdout's width in columns, or 0 for plain output or
+// when the size is unknown
+func terminalCols() int {
+	if plainOutput {
+		return 0
+	}
+	_, cols, err := terminalSize(os.Stdout.Fd())
+	if err != nil {
+		return 0
+	}
+	return cols
+}
+
+// fitWidth cuts each line of s that's too long for cols columns, ending it
+// with "…", so a narrow terminal doesn't wrap it over the lines below.
+// Width is counted in columns, two for CJK characters and emoji. A few
+// columns are kept spare for symbols terminals disagree on. cols <= 0
+// leaves s alone.
+func fitWidth(s string, cols int) string {
+	if cols <= 0 {
+		return s
+	}
+	limit := cols - 4
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if stringWidth(line) <= limit {
+			continue
+		}
+		if limit < 2 {
+			lines[i] = ""
+			continue
+		}
+		cut, _ := cutWidth(line, limit-1)
+		lines[i] = cut + "…"
+	}
+	return strings.Join(lines, "\n")
+}
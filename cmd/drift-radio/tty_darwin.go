@@ -0,0 +1,9 @@
+package main
+
+import "syscall"
+
+// ioctl requests that read and write the terminal settings
+const (
+	ioctlGetTermios = syscall.TIOCGETA
+	ioctlSetTermios = syscall.TIOCSETA
+)
@@ -0,0 +1,22 @@
+//go:build !linux && !darwin
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// enableKeyInput isn't implemented here; input stays line-buffered and
+// history recall is unavailable
+func enableKeyInput(fd uintptr) (func(), error) {
+	return nil, errors.New("key-by-key input is only supported on Linux and macOS")
+}
+
+// terminalSize isn't implemented here, so there's no status line
+func terminalSize(fd uintptr) (int, int, error) {
+	return 0, 0, errors.New("terminal size is only available on Linux and macOS")
+}
+
+// notifyResize does nothing without terminalSize
+func notifyResize(c chan<- os.Signal) {}
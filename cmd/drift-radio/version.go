@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// Build info, set at link time:
+//
+//	go build -ldflags "-X main.version=v1.2.0 -X main.commit=$(git rev-parse HEAD) -X main.buildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd/drift-radio
+//
+// Whatever isn't set is read from the module's build info, which go
+// install and go build record on their own.
+var (
+	version   string
+	commit    string
+	buildDate string
+)
+
+// buildInfo returns the version, commit, and build date of this binary,
+// with "unknown" for what neither the linker flags nor Go's build info
+// say. A build from a modified checkout has "-dirty" on its commit.
+func buildInfo() (ver, rev, date string) {
+	ver, rev, date = version, commit, buildDate
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if ver == "" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+			ver = info.Main.Version
+		}
+		var dirty bool
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if rev == "" {
+					rev = s.Value[:min(len(s.Value), 12)]
+				}
+			case "vcs.time":
+				if date == "" {
+					date = s.Value
+				}
+			case "vcs.modified":
+				dirty = s.Value == "true"
+			}
+		}
+		if dirty && commit == "" && rev != "" {
+			rev += "-dirty"
+		}
+	}
+	if ver == "" {
+		ver = "devel"
+	}
+	if rev == "" {
+		rev = "unknown"
+	}
+	if date == "" {
+		date = "unknown"
+	}
+	return ver, rev, date
+}
+
+// versionText is what -version prints, e.g. "drift-radio v1.2.0 (commit
+// 3dbdada4c1e2, built 2026-10-17T09:00:00Z, go1.24.2 linux/amd64)"
+func versionText() string {
+	ver, rev, date := buildInfo()
+	return fmt.Sprintf("drift-radio %s (commit %s, built %s, %s %s/%s)", ver, rev, date, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"sort"
+	"unicode"
+)
+
+// wideRanges lists the characters terminals draw two columns wide: the
+// East Asian Wide and Fullwidth blocks (CJK, Hangul, kana, fullwidth
+// forms) and the emoji that default to emoji presentation. Sorted, for
+// binary search.
+var wideRanges = [][2]rune{
+	{0x1100, 0x115F},
+	{0x231A, 0x231B},
+	{0x2329, 0x232A},
+	{0x23E9, 0x23EC},
+	{0x23F0, 0x23F0},
+	{0x23F3, 0x23F3},
+	{0x25FD, 0x25FE},
+	{0x2614, 0x2615},
+	{0x2648, 0x2653},
+	{0x267F, 0x267F},
+	{0x2693, 0x2693},
+	{0x26A1, 0x26A1},
+	{0x26AA, 0x26AB},
+	{0x26BD, 0x26BE},
+	{0x26C4, 0x26C5},
+	{0x26CE, 0x26CE},
+	{0x26D4, 0x26D4},
+	{0x26EA, 0x26EA},
+	{0x26F2, 0x26F3},
+	{0x26F5, 0x26F5},
+	{0x26FA, 0x26FA},
+	{0x26FD, 0x26FD},
+	{0x2705, 0x2705},
+	{0x270A, 0x270B},
+	{0x2728, 0x2728},
+	{0x274C, 0x274C},
+	{0x274E, 0x274E},
+	{0x2753, 0x2755},
+	{0x2757, 0x2757},
+	{0x2795, 0x2797},
+	{0x27B0, 0x27B0},
+	{0x27BF, 0x27BF},
+	{0x2B1B, 0x2B1C},
+	{0x2B50, 0x2B50},
+	{0x2B55, 0x2B55},
+	{0x2E80, 0x303E},
+	{0x3041, 0x33FF},
+	{0x3400, 0x4DBF},
+	{0x4E00, 0x9FFF},
+	{0xA000, 0xA4CF},
+	{0xA960, 0xA97F},
+	{0xAC00, 0xD7A3},
+	{0xF900, 0xFAFF},
+	{0xFE10, 0xFE19},
+	{0xFE30, 0xFE6F},
+	{0xFF00, 0xFF60},
+	{0xFFE0, 0xFFE6},
+	{0x16FE0, 0x16FE4},
+	{0x17000, 0x18AFF},
+	{0x1B000, 0x1B2FF},
+	{0x1F004, 0x1F004},
+	{0x1F0CF, 0x1F0CF},
+	{0x1F18E, 0x1F18E},
+	{0x1F191, 0x1F19A},
+	{0x1F200, 0x1F251},
+	{0x1F300, 0x1F64F},
+	{0x1F680, 0x1F6FF},
+	{0x1F7E0, 0x1F7EB},
+	{0x1F90C, 0x1F9FF},
+	{0x1FA70, 0x1FAFF},
+	{0x20000, 0x2FFFD},
+	{0x30000, 0x3FFFD},
+}
+
+// emojiPresentation is the variation selector that asks for the emoji
+// form of the character before it, drawn two columns wide, as in "⚠️"
+const emojiPresentation = '\uFE0F'
+
+// runeWidth returns the terminal columns r takes: 2 for wide characters,
+// 0 for combining marks and other zero-width characters, 1 otherwise
+func runeWidth(r rune) int {
+	if r == 0x200D || unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf, unicode.Variation_Selector) {
+		return 0
+	}
+	i := sort.Search(len(wideRanges), func(i int) bool { return wideRanges[i][1] >= r })
+	if i < len(wideRanges) && wideRanges[i][0] <= r {
+		return 2
+	}
+	return 1
+}
+
+// stringWidth returns the terminal columns s takes on one line
+func stringWidth(s string) int {
+	_, width := cutWidth(s, -1)
+	return width
+}
+
+// cutWidth returns the longest start of s that fits in limit columns, and
+// its width. A negative limit takes all of s.
+func cutWidth(s string, limit int) (string, int) {
+	width, prev := 0, 0
+	for i, r := range s {
+		w := runeWidth(r)
+		if r == emojiPresentation && prev == 1 {
+			// A text-style symbol drawn as emoji
+			w = 1
+		}
+		if limit >= 0 && width+w > limit {
+			return s[:i], width
+		}
+		width += w
+		prev = w
+		if r == emojiPresentation {
+			prev = 0
+		}
+	}
+	return s, width
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStringWidth(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{"", 0},
+		{"Radio Paradise", 14},
+		{"café", 4},
+		{"cafe\u0301", 4}, // combining accent
+		{"東京", 4},
+		{"한국어", 6},
+		{"ｆｕｌｌ", 8},
+		{"🎵 Song", 7},
+		{"⚠️ alert", 8}, // emoji presentation selector
+		{"★", 1},
+	}
+	for _, tt := range tests {
+		if got := stringWidth(tt.s); got != tt.want {
+			t.Errorf("stringWidth(%q) = %d, want %d", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestFitWidthWideText(t *testing.T) {
+	const cols = 80
+	title := strings.Repeat("夜", 40)
+	line := statusText(70, true, "Tokyo FM", title)
+	got := fitWidth(line, cols)
+	if w := stringWidth(got); w > cols {
+		t.Errorf("fitWidth left %d columns for an %d-column terminal: %q", w, cols, got)
+	}
+	if !strings.HasSuffix(got, "…") {
+		t.Errorf("fitWidth(%q) = %q, want it cut with …", line, got)
+	}
+	// Short lines are left alone
+	if got := fitWidth("東京 FM", cols); got != "東京 FM" {
+		t.Errorf("fitWidth cut a line that fits: %q", got)
+	}
+}
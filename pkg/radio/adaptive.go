@@ -0,0 +1,129 @@
+package radio
+
+import (
+	"context"
+	"time"
+)
+
+// Adaptive quality steps yt-dlp stations down a quality level when the
+// network stays poor and back up once it has stayed good. The windows
+// differ, and "Fair" changes nothing, so a borderline connection doesn't
+// flap between levels.
+const (
+	adaptiveDowngradeAfter = 30 * time.Second // sustained Poor/Very Poor
+	adaptiveUpgradeAfter   = 2 * time.Minute  // sustained Good/Excellent
+)
+
+// qualityLevels orders the -quality values from lowest to highest
+var qualityLevels = []string{"low", "medium", "high"}
+
+// qualityRank returns a quality's position in qualityLevels
+func qualityRank(quality string) int {
+	for i, q := range qualityLevels {
+		if q == quality {
+			return i
+		}
+	}
+	return len(qualityLevels) - 1
+}
+
+// effectiveQuality is the quality streams are resolved at: the adaptive
+// downgrade when one is in effect, else the chosen quality. Callers hold
+// p.mu.
+func (p *Player) effectiveQuality() string {
+	if p.autoQuality != "" {
+		return p.autoQuality
+	}
+	return p.quality
+}
+
+// AdaptiveSwitch describes a quality change made by RunAdaptive
+type AdaptiveSwitch struct {
+	Quality string        // the quality switched to
+	Down    bool          // stepped down rather than back up
+	Network string        // the analyzer's network assessment
+	For     time.Duration // how long the network has been that way
+	Err     error         // the restart at the new quality failed
+}
+
+// RunAdaptive watches the analyzer's network assessment every interval and
+// re-resolves the playing yt-dlp station at a lower or higher quality when
+// it stays poor or good, until ctx is done. It never goes above the quality
+// chosen with SetQuality. onSwitch, when set, is told about each change
+// before the restart, and again with Err set if the restart fails.
+func (p *Player) RunAdaptive(ctx context.Context, interval time.Duration, onSwitch func(AdaptiveSwitch)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var badSince, goodSince time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		p.mu.Lock()
+		playing := p.cmd != nil && !p.isStopped && NeedsResolution(p.playingURL)
+		url := p.playingURL
+		current := p.effectiveQuality()
+		chosen := p.quality
+		p.mu.Unlock()
+		if !playing {
+			badSince, goodSince = time.Time{}, time.Time{}
+			continue
+		}
+
+		now := time.Now()
+		network := p.analyzer.GetStats().NetworkQuality
+		switch network {
+		case "Poor", "Very Poor":
+			goodSince = time.Time{}
+			if badSince.IsZero() {
+				badSince = now
+			}
+		case "Good", "Excellent":
+			badSince = time.Time{}
+			if goodSince.IsZero() {
+				goodSince = now
+			}
+		default:
+			badSince, goodSince = time.Time{}, time.Time{}
+		}
+
+		rank := qualityRank(current)
+		var target string
+		var since time.Time
+		switch {
+		case !badSince.IsZero() && now.Sub(badSince) >= adaptiveDowngradeAfter && rank > 0:
+			target, since = qualityLevels[rank-1], badSince
+		case !goodSince.IsZero() && now.Sub(goodSince) >= adaptiveUpgradeAfter && rank < qualityRank(chosen):
+			target, since = qualityLevels[rank+1], goodSince
+		default:
+			continue
+		}
+		badSince, goodSince = time.Time{}, time.Time{}
+
+		p.mu.Lock()
+		if p.playingURL != url || p.isStopped {
+			// The station changed or stopped while we were deciding
+			p.mu.Unlock()
+			continue
+		}
+		if target == p.quality {
+			p.autoQuality = ""
+		} else {
+			p.autoQuality = target
+		}
+		p.mu.Unlock()
+
+		change := AdaptiveSwitch{Quality: target, Down: qualityRank(target) < rank, Network: network, For: now.Sub(since)}
+		if onSwitch != nil {
+			onSwitch(change)
+		}
+		if err := p.Restart(url); err != nil && onSwitch != nil {
+			change.Err = err
+			onSwitch(change)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package radio
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// splitArgs splits a command line the way a POSIX shell would for plain
+// words: whitespace separates arguments, single and double quotes group
+// them, and a backslash escapes the next character outside single quotes.
+func splitArgs(s string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		inWord  bool
+		quote   rune
+		escaped bool
+	)
+	for _, r := range s {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				args = append(args, current.String())
+				current.Reset()
+				inWord = false
+			}
+		default:
+			current.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("unterminated quote in arguments")
+	}
+	if escaped {
+		return nil, errors.New("trailing backslash in arguments")
+	}
+	if inWord {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// shellSafeRegexp matches arguments a POSIX shell reads as is
+var shellSafeRegexp = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// shellQuote quotes an argument for a POSIX shell. Arguments with control
+// characters (ffplay's CRLF-separated -headers) use bash's $'...' form so
+// the printed line stays on one line.
+func shellQuote(arg string) string {
+	if shellSafeRegexp.MatchString(arg) {
+		return arg
+	}
+	if strings.ContainsAny(arg, "\r\n\t") {
+		r := strings.NewReplacer(`\`, `\\`, "'", `\'`, "\r", `\r`, "\n", `\n`, "\t", `\t`)
+		return "$'" + r.Replace(arg) + "'"
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// commandLine formats a command for copying into a shell, with env
+// assignments (NAME=value) in front
+func commandLine(env []string, args []string) string {
+	words := make([]string, 0, len(env)+len(args))
+	for _, kv := range env {
+		name, value, _ := strings.Cut(kv, "=")
+		words = append(words, name+"="+shellQuote(value))
+	}
+	for _, arg := range args {
+		words = append(words, shellQuote(arg))
+	}
+	return strings.Join(words, " ")
+}
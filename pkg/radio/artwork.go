@@ -0,0 +1,39 @@
+package radio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Thumbnail asks yt-dlp for the thumbnail image of a page URL, such as a
+// YouTube video's. YouTube's WebP thumbnails are swapped for the JPEG
+// copies it serves alongside them, which more programs can decode.
+func Thumbnail(pageURL string, opts ResolveOptions) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+	args := append([]string{"--get-thumbnail", "--no-warnings"}, opts.ytdlpArgs()...)
+	cmd := exec.CommandContext(ctx, ytdlpBinary, append(args, pageURL)...)
+	cmd.WaitDelay = time.Second
+	var stdout, stderr strings.Builder
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := ytdlpErrorLine(stderr.String()); msg != "" {
+			return "", fmt.Errorf("yt-dlp failed: %s", msg)
+		}
+		return "", err
+	}
+	thumb, _, _ := strings.Cut(strings.TrimSpace(stdout.String()), "\n")
+	if thumb == "" {
+		return "", errors.New("yt-dlp found no thumbnail")
+	}
+	if strings.Contains(thumb, "ytimg.com/vi_webp/") && strings.HasSuffix(thumb, ".webp") {
+		thumb = strings.Replace(thumb, "/vi_webp/", "/vi/", 1)
+		thumb = strings.TrimSuffix(thumb, ".webp") + ".jpg"
+	}
+	return thumb, nil
+}
@@ -0,0 +1,188 @@
+package radio
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Playback backends
+const (
+	BackendFFplay = "ffplay"
+	BackendMPV    = "mpv"
+	BackendPCM    = "ffmpeg" // decodes to raw PCM instead of playing; see WithPCM
+)
+
+// installHints tells the user how to get each backend's binary
+var installHints = map[string]string{
+	BackendFFplay: "Please install FFmpeg: sudo apt install ffmpeg",
+	BackendMPV:    "Please install mpv: sudo apt install mpv",
+	BackendPCM:    "Please install FFmpeg: sudo apt install ffmpeg",
+}
+
+// The raw PCM format WithPCM writes: signed 16-bit little-endian samples,
+// interleaved
+const (
+	PCMSampleRate = 44100
+	PCMChannels   = 2
+)
+
+// monoFilter downmixes any channel layout to mono; ffmpeg's resampler
+// mixes the channels down rather than dropping all but the first
+const monoFilter = "aformat=channel_layouts=mono"
+
+// audioFilters returns the ffmpeg filters shared by every backend: the
+// mono downmix, loudness normalization, then volume (ffplay passes its
+// volume filter; mpv sets volume itself), EQ, and the fade-in.
+// Normalization comes before volume so it measures the station as
+// mastered and can't undo volume changes.
+func (p *Player) audioFilters(volume string) []string {
+	var filters []string
+	if p.mono {
+		filters = append(filters, monoFilter)
+	}
+	if p.loudnorm != 0 {
+		filters = append(filters, loudnormFilter(p.loudnorm))
+	}
+	if volume != "" {
+		filters = append(filters, volume)
+	}
+	if eq, err := eqFilter(p.eqPreset); err == nil && eq != "" {
+		filters = append(filters, eq)
+	}
+	fade := p.fade
+	if p.cutIn {
+		fade = 0
+	}
+	if p.rampIn > 0 {
+		fade = p.rampIn
+	}
+	if fadeIn := fadeInFilter(fade); fadeIn != "" {
+		filters = append(filters, fadeIn)
+	}
+	return filters
+}
+
+func (p *Player) mpvArgs(url string) []string {
+	args := []string{
+		"--no-video",
+		"--no-terminal", // Keep mpv off stdin so it doesn't fight the radio> prompt
+		fmt.Sprintf("--volume=%d", p.volumePercent),
+		"--input-ipc-server=" + p.ipcPath,
+	}
+	if p.boost {
+		// mpv stops at 130% by default
+		args = append(args, fmt.Sprintf("--volume-max=%d", MaxBoostVolume))
+	}
+	if p.device != "" {
+		args = append(args, "--audio-device="+p.device)
+	}
+	for _, line := range headerLines(p.headers) {
+		args = append(args, "--http-header-fields-append="+line)
+	}
+	if p.loopFile {
+		args = append(args, "--loop-file=inf")
+	}
+	if p.lowLatency {
+		args = append(args, "--profile=low-latency")
+	}
+	if p.prebuffer > 0 {
+		args = append(args, "--cache=yes", "--cache-pause-initial=yes", "--cache-pause-wait="+strconv.FormatFloat(p.prebuffer.Seconds(), 'f', -1, 64))
+	}
+	if filters := p.audioFilters(""); len(filters) > 0 {
+		args = append(args, "--af=lavfi=["+strings.Join(filters, ",")+"]")
+	}
+	args = append(args, p.extraArgs...)
+	return append(args, url)
+}
+
+// pcmArgs has ffmpeg decode url to raw PCM on its stdout, in real time
+// so the reader hears it as it would have played. Volume is left to the
+// reader; the other filters still apply.
+func (p *Player) pcmArgs(url string) []string {
+	return p.decoderArgs(url, true, p.audioFilters(""), "s16le")
+}
+
+// decoderArgs has ffmpeg decode url through filters to PCM in format on
+// its stdout, at the playback rate when realtime is set, or as fast as
+// the input comes otherwise
+func (p *Player) decoderArgs(url string, realtime bool, filters []string, format string) []string {
+	args := []string{"-nostdin", "-loglevel", p.ffmpegLogLevel(), "-hide_banner"}
+	if realtime {
+		args = append(args, "-re")
+	}
+	if p.reconnect && isHTTPURL(url) {
+		args = append(args, "-reconnect", "1", "-reconnect_streamed", "1", "-reconnect_delay_max", "5")
+	}
+	if len(p.headers) > 0 && isHTTPURL(url) {
+		args = append(args, "-headers", ffmpegHeaders(p.headers))
+	}
+	if p.loopFile {
+		args = append(args, "-stream_loop", "-1")
+	}
+	args = append(args, "-i", url)
+	if len(filters) > 0 {
+		args = append(args, "-af", strings.Join(filters, ","))
+	}
+	return append(args, "-vn", "-f", format, "-ar", strconv.Itoa(PCMSampleRate), "-ac", strconv.Itoa(PCMChannels), "-")
+}
+
+// ffmpegLogLevel returns the -loglevel for ffplay and ffmpeg
+func (p *Player) ffmpegLogLevel() string {
+	if p.quiet {
+		return "error"
+	}
+	return "warning"
+}
+
+// mpvIPCPath returns a per-process path for mpv's JSON IPC server
+func mpvIPCPath() string {
+	name := fmt.Sprintf("drift-radio-mpv-%d", os.Getpid())
+	if ipcUsesNamedPipes {
+		return `\\.\pipe\` + name
+	}
+	return filepath.Join(os.TempDir(), name+".sock")
+}
+
+// mpvCommand sends one command to mpv's JSON IPC server and waits for the
+// reply, e.g. mpvCommand(path, "set_property", "volume", 50).
+func mpvCommand(path string, command ...any) error {
+	conn, err := DialIPC(path, time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+	req, err := json.Marshal(map[string]any{"command": command})
+	if err != nil {
+		return err
+	}
+	if _, err := conn.Write(append(req, '\n')); err != nil {
+		return err
+	}
+
+	// mpv may interleave event messages; the reply is the line with "error"
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		var reply struct {
+			Error *string `json:"error"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &reply); err != nil || reply.Error == nil {
+			continue
+		}
+		if *reply.Error != "success" {
+			return fmt.Errorf("mpv: %s", *reply.Error)
+		}
+		return nil
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("mpv closed the IPC connection without replying")
+}
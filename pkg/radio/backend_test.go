@@ -0,0 +1,56 @@
+package radio
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAudioFilters(t *testing.T) {
+	const volume = "volume=-10.000000dB"
+	tests := []struct {
+		name  string
+		setup func(p *Player)
+		want  []string
+	}{
+		{"nothing on", func(*Player) {}, []string{volume}},
+		{"flat EQ", func(p *Player) { _ = p.SetEQ("flat") }, []string{volume}},
+		{"mono", func(p *Player) { p.SetMono(true) }, []string{"aformat=channel_layouts=mono", volume}},
+		{"EQ", func(p *Player) { _ = p.SetEQ("bass") }, []string{volume, "bass=g=6:f=110"}},
+		{"fade", func(p *Player) { p.SetFade(2 * time.Second) }, []string{volume, "afade=t=in:d=2.000"}},
+		{"everything", func(p *Player) {
+			p.SetFade(2 * time.Second)
+			_ = p.SetEQ("bass")
+			_ = p.SetNormalize(-16)
+			p.SetMono(true)
+		}, []string{
+			"aformat=channel_layouts=mono",
+			"loudnorm=I=-16:TP=-1.5:LRA=11,aresample=48000",
+			volume,
+			"bass=g=6:f=110",
+			"afade=t=in:d=2.000",
+		}},
+	}
+	for _, tt := range tests {
+		p := NewPlayer()
+		p.SetVolume(50)
+		tt.setup(p)
+		if got, want := afArg(t, p), strings.Join(tt.want, ","); got != want {
+			t.Errorf("%s: -af %q, want %q", tt.name, got, want)
+		}
+	}
+}
+
+func TestAudioFiltersNone(t *testing.T) {
+	// Without a volume stage, as for mpv, nothing on means no filters
+	p := NewPlayer()
+	_ = p.SetEQ("flat")
+	if filters := p.audioFilters(""); len(filters) != 0 {
+		t.Errorf("audioFilters = %q, want none", filters)
+	}
+	for _, arg := range p.mpvArgs("station.mp3") {
+		if strings.HasPrefix(arg, "--af") {
+			t.Errorf("mpv was given a filter chain: %q", arg)
+		}
+	}
+}
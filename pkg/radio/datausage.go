@@ -0,0 +1,61 @@
+package radio
+
+import "time"
+
+// TrackProcess measures the data used by the player process with the
+// given PID. Call it after each Start; StopAnalysis takes a final sample.
+func (sa *StreamAnalyzer) TrackProcess(pid int) {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	sa.pid = pid
+	sa.pidBytes = 0
+	sa.pidSampled = time.Now()
+}
+
+// SetDataCap calls onCap once when the session's data use reaches capBytes.
+// A cap of 0 disables it.
+func (sa *StreamAnalyzer) SetDataCap(capBytes int64, onCap func(used int64)) {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	sa.dataCap = capBytes
+	sa.onDataCap = onCap
+	sa.capReached = false
+}
+
+// sampleProcessLocked adds the bytes the tracked process read since the
+// last sample to the stream and session totals. It returns the measured
+// download speed, or false if the process can't be measured.
+func (sa *StreamAnalyzer) sampleProcessLocked(now time.Time) (float64, bool) {
+	if sa.pid == 0 {
+		return 0, false
+	}
+	n, err := processReadBytes(sa.pid)
+	if err != nil {
+		return 0, false
+	}
+	delta := n - sa.pidBytes
+	if delta < 0 {
+		delta = 0
+	}
+	elapsed := now.Sub(sa.pidSampled).Seconds()
+	sa.pidBytes = n
+	sa.pidSampled = now
+
+	sa.sessionBytes += delta
+	sa.stats.TotalBytes += delta
+	sa.stats.SessionBytes = sa.sessionBytes
+	sa.stats.DataMeasured = true
+
+	if sa.dataCap > 0 && !sa.capReached && sa.sessionBytes >= sa.dataCap {
+		sa.capReached = true
+		if sa.onDataCap != nil {
+			// Run outside the lock; the handler may stop the player
+			go sa.onDataCap(sa.sessionBytes)
+		}
+	}
+
+	if elapsed <= 0 {
+		return 0, false
+	}
+	return float64(delta) / elapsed, true
+}
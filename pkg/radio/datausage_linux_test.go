@@ -0,0 +1,60 @@
+package radio
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+// readerURLEnv tells a re-run of the test binary to act as a player,
+// reading the stream at that URL until it's killed
+const readerURLEnv = "DRIFT_RADIO_TEST_READ_URL"
+
+func TestHelperStreamReader(t *testing.T) {
+	url := os.Getenv(readerURLEnv)
+	if url == "" {
+		t.Skip("only run as a stand-in player")
+	}
+	resp, err := http.Get(url)
+	if err != nil {
+		os.Exit(1)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	os.Exit(0)
+}
+
+func TestAnalyzerDownloadSpeed(t *testing.T) {
+	const rate = 64 * 1024
+	url := startFakeStream(t, &fakeStream{bytesPerSecond: rate})
+
+	reader := exec.Command(os.Args[0], "-test.run=^TestHelperStreamReader$")
+	reader.Env = append(os.Environ(), readerURLEnv+"="+url)
+	if err := reader.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = reader.Process.Kill()
+		_ = reader.Wait()
+	})
+
+	sa := newTestAnalyzer(t)
+	// Long enough for several of the server's writes per sample
+	sa.setIntervals(500*time.Millisecond, time.Minute)
+	if err := sa.StartAnalysis(t.Context(), url); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(sa.StopAnalysis)
+	sa.TrackProcess(reader.Process.Pid)
+
+	// The first sample may cover the reader's startup; judge a later one
+	stats := waitStats(t, sa, func(s StreamStats) bool { return s.TotalBytes >= 2*rate })
+	if !stats.DataMeasured {
+		t.Fatal("the reader's data use wasn't measured")
+	}
+	if stats.DownloadSpeed < rate/2 || stats.DownloadSpeed > rate*3/2 {
+		t.Errorf("download speed = %.0f B/s, want about %d", stats.DownloadSpeed, rate)
+	}
+}
@@ -0,0 +1,44 @@
+package radio
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+)
+
+// Detach lets go of the playing stream without stopping it and returns the
+// player's PID. The player keeps running after the program exits; stop it
+// later with StopDetached. A directory station stops after its current
+// track, since moving on to the next is up to this Player. It needs
+// WithDetach, so the player isn't tied to the terminal.
+func (p *Player) Detach() (int, error) {
+	defer p.changed()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.detach {
+		return 0, errors.New("the player wasn't started detachable")
+	}
+	if p.cmd == nil || p.cmd.Process == nil {
+		return 0, errors.New("not playing")
+	}
+	pid := p.cmd.Process.Pid
+	p.log().Debug("detaching player", "pid", pid)
+	p.analyzer.StopAnalysis()
+	// The goroutine waiting on cmd sees it's no longer ours and leaves it be
+	p.cmd = nil
+	p.isStopped = true
+	return pid, nil
+}
+
+// StopDetached stops a player that Detach left running. backend is the
+// player's program; where process names can be read, a PID that has been
+// reused by something else is left alone.
+func StopDetached(pid int, backend string) error {
+	if name, err := processName(pid); err == nil && name != filepath.Base(backend) {
+		return fmt.Errorf("process %d is %s, not %s: %w", pid, name, backend, ErrDetachedGone)
+	}
+	return stopDetachedProcess(pid)
+}
+
+// ErrDetachedGone means the detached player has already exited
+var ErrDetachedGone = errors.New("the player is no longer running")
@@ -0,0 +1,27 @@
+//go:build !windows
+
+package radio
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// detachedProcAttr puts the player in a new session, so closing the
+// terminal doesn't hang it up
+func detachedProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
+
+func stopDetachedProcess(pid int) error {
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	err = proc.Signal(syscall.SIGTERM)
+	if errors.Is(err, os.ErrProcessDone) {
+		return ErrDetachedGone
+	}
+	return err
+}
@@ -0,0 +1,26 @@
+//go:build windows
+
+package radio
+
+import (
+	"os"
+	"syscall"
+)
+
+// detachedProcess is Windows' DETACHED_PROCESS creation flag
+const detachedProcess = 0x00000008
+
+// detachedProcAttr starts the player without the console, in a process
+// group of its own so Ctrl+C in the console doesn't reach it
+func detachedProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP | detachedProcess}
+}
+
+func stopDetachedProcess(pid int) error {
+	// FindProcess fails for a PID that isn't running
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return ErrDetachedGone
+	}
+	return proc.Kill()
+}
@@ -0,0 +1,111 @@
+package radio
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// AudioDevice is an output device the player can be pointed at
+type AudioDevice struct {
+	Name        string
+	Description string
+}
+
+// mpvDeviceRegexp matches a line of `mpv --audio-device=help`:
+//
+//	'pulse/alsa_output.pci-0000_00_1f.3.analog-stereo' (Built-in Audio Analog Stereo)
+var mpvDeviceRegexp = regexp.MustCompile(`^\s*'([^']+)'\s*\((.*)\)\s*$`)
+
+// ListAudioDevices enumerates output devices for the backend. mpv lists
+// its own; for ffplay the sound server's sinks are listed, from pactl
+// (PulseAudio/PipeWire) or else aplay (ALSA).
+func ListAudioDevices(backend string) ([]AudioDevice, error) {
+	if backend == BackendMPV {
+		out, err := exec.Command("mpv", "--audio-device=help").Output()
+		if err != nil {
+			return nil, fmt.Errorf("mpv --audio-device=help: %w", err)
+		}
+		var devices []AudioDevice
+		for _, line := range strings.Split(string(out), "\n") {
+			if m := mpvDeviceRegexp.FindStringSubmatch(line); m != nil {
+				devices = append(devices, AudioDevice{Name: m[1], Description: m[2]})
+			}
+		}
+		return devices, nil
+	}
+
+	if _, err := exec.LookPath("pactl"); err == nil {
+		out, err := exec.Command("pactl", "list", "short", "sinks").Output()
+		if err != nil {
+			return nil, fmt.Errorf("pactl list short sinks: %w", err)
+		}
+		var devices []AudioDevice
+		for _, line := range strings.Split(string(out), "\n") {
+			// index, name, driver, sample spec, state
+			fields := strings.Split(line, "\t")
+			if len(fields) >= 2 {
+				devices = append(devices, AudioDevice{Name: fields[1]})
+			}
+		}
+		return devices, nil
+	}
+	if _, err := exec.LookPath("aplay"); err == nil {
+		out, err := exec.Command("aplay", "-L").Output()
+		if err != nil {
+			return nil, fmt.Errorf("aplay -L: %w", err)
+		}
+		return parseAplayDevices(string(out)), nil
+	}
+	return nil, errors.New("no device list available for ffplay: install pactl (PulseAudio/PipeWire) or aplay (ALSA), or use -backend mpv")
+}
+
+// parseAplayDevices reads `aplay -L`: device names start a line and the
+// indented lines after one describe it
+func parseAplayDevices(out string) []AudioDevice {
+	var devices []AudioDevice
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		if line[0] != ' ' && line[0] != '\t' {
+			devices = append(devices, AudioDevice{Name: line})
+			continue
+		}
+		if n := len(devices); n > 0 && devices[n-1].Description == "" {
+			devices[n-1].Description = strings.TrimSpace(line)
+		}
+	}
+	return devices
+}
+
+// playerEnvVars returns the variables the player process gets on top of
+// ours: an HTTP proxy, and the output device for ffplay, which has no
+// device flag; its SDL audio output honors PULSE_SINK (PulseAudio/PipeWire)
+// and AUDIODEV (ALSA) instead.
+func (p *Player) playerEnvVars() []string {
+	var vars []string
+	if IsHTTPProxy(p.proxy) {
+		vars = append(vars, "http_proxy="+p.proxy.String())
+	}
+	if p.device != "" && p.backend != BackendMPV {
+		vars = append(vars, "PULSE_SINK="+p.device, "AUDIODEV="+p.device)
+	}
+	return vars
+}
+
+// playerEnv returns the environment for the player process, or nil to
+// inherit ours
+func (p *Player) playerEnv() []string {
+	vars := p.playerEnvVars()
+	if len(vars) == 0 {
+		return nil
+	}
+	return append(os.Environ(), vars...)
+}
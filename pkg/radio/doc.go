@@ -0,0 +1,37 @@
+// Package radio is the playback engine behind drift-radio. It plays
+// internet radio streams and yt-dlp page URLs (YouTube, SoundCloud, ...)
+// through ffplay or mpv, and measures the stream while it plays.
+//
+// A Player plays one stream at a time:
+//
+//	p := radio.NewPlayer(
+//		radio.WithBackend(radio.BackendMPV),
+//		radio.WithVolumeCurve(radio.CurvePerceptual),
+//	)
+//	if err := radio.CheckDependencies(p.Backend()); err != nil {
+//		log.Fatal(err)
+//	}
+//	p.SetVolume(60)
+//	if err := p.Start("https://www.youtube.com/watch?v=jfKfPfyJRdk"); err != nil {
+//		log.Fatal(err)
+//	}
+//	defer p.Stop()
+//
+// Settings that ffplay reads at startup (EQ, quality, and, with ffplay,
+// volume) apply from the next Start; Restart applies them to the playing
+// stream. Pause and Resume stop and restart a stream without resolving it
+// again.
+//
+// Each Player has a StreamAnalyzer, which probes the stream in the
+// background while it plays:
+//
+//	stats := p.Analyzer().GetStats()
+//	fmt.Printf("%d kbps, network %s\n", stats.Bitrate/1000, stats.NetworkQuality)
+//
+// Pass radio.WithAnalyzer(false) to skip the probes. OnChange reports
+// playback and volume changes, e.g. to keep a display up to date:
+//
+//	p.OnChange(func(st radio.PlayerStatus) {
+//		log.Printf("%s playing=%v volume=%d%%", st.Name, st.Playing, st.Volume)
+//	})
+package radio
@@ -0,0 +1,57 @@
+package radio
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// eqPresets maps preset names to ffmpeg audio filter chains
+var eqPresets = map[string]string{
+	"flat":   "",
+	"bass":   "bass=g=6:f=110",
+	"treble": "treble=g=5:f=3000",
+	"vocal":  "equalizer=f=250:t=o:w=1:g=-2,equalizer=f=2500:t=o:w=1:g=4",
+}
+
+// EQPresetNames returns the preset names in a stable order
+func EQPresetNames() []string {
+	names := make([]string, 0, len(eqPresets))
+	for name := range eqPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// eqFilter returns the ffmpeg filter chain for an EQ setting.
+// The setting is either a preset name or a custom gains string of
+// comma-separated freq:gain pairs, e.g. "60:4,1000:-2,8000:3".
+func eqFilter(setting string) (string, error) {
+	setting = strings.ToLower(strings.TrimSpace(setting))
+	if setting == "" {
+		return "", nil
+	}
+	if chain, ok := eqPresets[setting]; ok {
+		return chain, nil
+	}
+
+	var bands []string
+	for _, pair := range strings.Split(setting, ",") {
+		freqStr, gainStr, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			return "", fmt.Errorf("unknown EQ preset %q (presets: %s, or freq:gain pairs)", setting, strings.Join(EQPresetNames(), ", "))
+		}
+		freq, err := strconv.ParseFloat(freqStr, 64)
+		if err != nil || freq <= 0 {
+			return "", fmt.Errorf("invalid EQ frequency %q", freqStr)
+		}
+		gain, err := strconv.ParseFloat(gainStr, 64)
+		if err != nil || gain < -20 || gain > 20 {
+			return "", fmt.Errorf("invalid EQ gain %q (must be -20..20 dB)", gainStr)
+		}
+		bands = append(bands, fmt.Sprintf("equalizer=f=%g:t=o:w=1:g=%g", freq, gain))
+	}
+	return strings.Join(bands, ","), nil
+}
@@ -0,0 +1,124 @@
+package radio
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxFade bounds fades so quitting never hangs on a long fade-out
+const MaxFade = 10 * time.Second
+
+// fadeStep is how often the fade-out volume is lowered
+const fadeStep = 50 * time.Millisecond
+
+// fadeInFilter returns the afade filter that ramps audio up from silence
+func fadeInFilter(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("afade=t=in:d=%.3f", d.Seconds())
+}
+
+// fadeDrain is how long a frozen player's buffered audio gets to play out
+// at the faded volume before the volume is put back
+const fadeDrain = 250 * time.Millisecond
+
+// fadeOut lowers the volume of the process's audio stream to zero over d.
+// ffplay can't change its filter chain while running, so the ramp is done
+// on the sound server's per-stream volume via pactl (PulseAudio/PipeWire).
+// It returns immediately if that isn't available, and early if abort is
+// closed. Either way the stream's volume is put back before it returns:
+// the sound server remembers per-application volumes, and would start the
+// next stream at the faded level. So the ramp-down isn't undone audibly,
+// the process is frozen first where that's possible; stopLocked thaws it
+// along with the kill.
+func fadeOut(proc *os.Process, d time.Duration, abort <-chan struct{}) {
+	if d <= 0 {
+		return
+	}
+	if d > MaxFade {
+		d = MaxFade
+	}
+	if _, err := exec.LookPath("pactl"); err != nil {
+		return
+	}
+	list := exec.Command("pactl", "list", "sink-inputs")
+	// The labels looked for below are translated in other locales
+	list.Env = append(os.Environ(), "LC_ALL=C")
+	out, err := list.Output()
+	if err != nil {
+		return
+	}
+	input, volume, ok := sinkInputForPID(string(out), proc.Pid)
+	if !ok {
+		return
+	}
+	defer restoreVolume(proc, input, volume)
+
+	steps := int(d / fadeStep)
+	ticker := time.NewTicker(fadeStep)
+	defer ticker.Stop()
+	for i := steps - 1; i >= 0; i-- {
+		select {
+		case <-abort:
+			return
+		case <-ticker.C:
+			level := fmt.Sprintf("%d%%", i*100/steps)
+			if err := exec.Command("pactl", "set-sink-input-volume", input, level).Run(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// restoreVolume puts a faded sink input back at volume, once the process
+// playing it is frozen and what it had buffered has played out
+func restoreVolume(proc *os.Process, input string, volume []string) {
+	if len(volume) == 0 {
+		return
+	}
+	if freezeProcess(proc) {
+		time.Sleep(fadeDrain)
+	}
+	args := append([]string{"set-sink-input-volume", input}, volume...)
+	_ = exec.Command("pactl", args...).Run()
+}
+
+// sinkInputForPID finds the pactl sink input index owned by pid, and its
+// volume as raw per-channel values for set-sink-input-volume
+func sinkInputForPID(list string, pid int) (string, []string, bool) {
+	want := strconv.Itoa(pid)
+	var (
+		current string
+		volume  []string
+	)
+	for _, line := range strings.Split(list, "\n") {
+		line = strings.TrimSpace(line)
+		if idx, ok := strings.CutPrefix(line, "Sink Input #"); ok {
+			current, volume = idx, nil
+			continue
+		}
+		if channels, ok := strings.CutPrefix(line, "Volume:"); ok {
+			volume = nil
+			for _, m := range rawVolumeRegexp.FindAllStringSubmatch(channels, -1) {
+				volume = append(volume, m[1])
+			}
+			continue
+		}
+		if value, ok := strings.CutPrefix(line, "application.process.id = "); ok {
+			if strings.Trim(value, `"`) == want && current != "" {
+				return current, volume, true
+			}
+		}
+	}
+	return "", nil, false
+}
+
+// rawVolumeRegexp matches one channel of a pactl Volume line, e.g.
+// "front-left: 65536 / 100% / 0.00 dB", capturing the raw value
+var rawVolumeRegexp = regexp.MustCompile(`[\w-]+:\s*(\d+)\s*/`)
@@ -0,0 +1,42 @@
+package radio
+
+import (
+	"slices"
+	"testing"
+)
+
+const sinkInputs = `Sink Input #41
+	Driver: protocol-native.c
+	Volume: front-left: 65536 / 100% / 0.00 dB,   front-right: 65536 / 100% / 0.00 dB
+	        balance 0.00
+	Properties:
+		application.name = "mpv"
+		application.process.id = "1200"
+
+Sink Input #42
+	Driver: protocol-native.c
+	Volume: front-left: 45875 / 70% / -9.29 dB,   front-right: 45875 / 70% / -9.29 dB
+	        balance 0.00
+	Properties:
+		application.name = "ffplay"
+		application.process.id = "1234"
+`
+
+func TestSinkInputForPID(t *testing.T) {
+	tests := []struct {
+		pid    int
+		input  string
+		volume []string
+		ok     bool
+	}{
+		{1200, "41", []string{"65536", "65536"}, true},
+		{1234, "42", []string{"45875", "45875"}, true},
+		{999, "", nil, false},
+	}
+	for _, tt := range tests {
+		input, volume, ok := sinkInputForPID(sinkInputs, tt.pid)
+		if input != tt.input || !slices.Equal(volume, tt.volume) || ok != tt.ok {
+			t.Errorf("sinkInputForPID(%d) = %q, %q, %v; want %q, %q, %v", tt.pid, input, volume, ok, tt.input, tt.volume, tt.ok)
+		}
+	}
+}
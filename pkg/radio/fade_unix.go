@@ -0,0 +1,20 @@
+//go:build !windows
+
+package radio
+
+import (
+	"os"
+	"syscall"
+)
+
+// freezeProcess stops proc from running, and so from feeding the sound
+// server, until thawProcess
+func freezeProcess(proc *os.Process) bool {
+	return proc.Signal(syscall.SIGSTOP) == nil
+}
+
+// thawProcess lets a process frozen by freezeProcess run again, e.g. to
+// handle the SIGTERM sent while it was frozen
+func thawProcess(proc *os.Process) {
+	_ = proc.Signal(syscall.SIGCONT)
+}
@@ -0,0 +1,14 @@
+//go:build windows
+
+package radio
+
+import "os"
+
+// freezeProcess isn't available on Windows, which has no pactl to fade
+// with anyway
+func freezeProcess(proc *os.Process) bool {
+	return false
+}
+
+// thawProcess has nothing to do on Windows; see freezeProcess
+func thawProcess(proc *os.Process) {}
@@ -0,0 +1,111 @@
+package radio
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// formatProbeTimeout bounds the ffprobe run before a stream starts. A
+// stream that doesn't answer in time starts with ffplay's defaults.
+const formatProbeTimeout = 5 * time.Second
+
+// streamFormat is what ffprobe says about a stream before it's played
+type streamFormat struct {
+	Duration  time.Duration // 0 for live streams
+	Codec     string        // audio codec, e.g. "opus" or "mp3"
+	Container string        // ffprobe's format names, e.g. "ogg"
+	Video     bool          // has a video track beside the audio
+	NoAudio   bool          // ffprobe read the stream and found no audio track
+}
+
+// ErrNoAudio is returned by Start for a stream that ffprobe finds has no
+// audio track, such as a video-only stream or a web page, rather than
+// starting a player that can't play it
+var ErrNoAudio = errors.New("no audio track found in this stream")
+
+// formatLocked asks ffprobe about resolved, a remote stream. The answer
+// is kept for restarts of the same stream, so volume and EQ changes don't
+// probe again. It's empty when ffprobe couldn't tell, or in a dry run that
+// runs nothing. Callers hold p.mu.
+func (p *Player) formatLocked(resolved string) streamFormat {
+	if resolved == p.formatURL {
+		return p.format
+	}
+	p.formatURL = resolved
+	p.format = streamFormat{}
+	if p.dryRun != nil && !p.dryRunResolve {
+		return p.format
+	}
+	f, err := p.probeFormat(resolved)
+	if err != nil {
+		p.log().Debug("could not probe stream format", "err", err)
+		return p.format
+	}
+	p.format = f
+	return f
+}
+
+// probeFormat runs ffprobe on url. Callers hold p.mu.
+func (p *Player) probeFormat(url string) (streamFormat, error) {
+	ctx, cancel := context.WithTimeout(p.ctx, formatProbeTimeout)
+	defer cancel()
+	args := []string{"-v", "quiet", "-print_format", "json", "-show_streams", "-show_format"}
+	if len(p.headers) > 0 && isHTTPURL(url) {
+		args = append(args, "-headers", ffmpegHeaders(p.headers))
+	}
+	p.analyzer.mu.RLock()
+	ffprobe := p.analyzer.ffprobe
+	p.analyzer.mu.RUnlock()
+	cmd := exec.CommandContext(ctx, ffprobe, append(args, url)...)
+	cmd.Env = proxyEnv(p.proxy)
+	cmd.WaitDelay = time.Second
+	output, err := cmd.Output()
+	if err != nil {
+		return streamFormat{}, err
+	}
+	return parseStreamFormat(output)
+}
+
+// parseStreamFormat reads ffprobe's JSON output for a stream
+func parseStreamFormat(output []byte) (streamFormat, error) {
+	var probeOutput FFProbeOutput
+	if err := json.Unmarshal(output, &probeOutput); err != nil {
+		return streamFormat{}, err
+	}
+	f := streamFormat{Container: probeOutput.Format.FormatName, Video: videoCodec(probeOutput.Streams) != ""}
+	audio := firstAudioStream(probeOutput.Streams)
+	if audio == nil {
+		f.NoAudio = true
+		return f, nil
+	}
+	f.Codec = audio.CodecName
+	// "N/A" or missing for live streams
+	if secs, err := strconv.ParseFloat(audio.Duration, 64); err == nil && secs > 0 {
+		f.Duration = time.Duration(secs * float64(time.Second))
+	}
+	return f, nil
+}
+
+// formatArgs returns ffplay input options suited to the stream. ffplay
+// often mis-probes Ogg and Opus live streams from its default sample,
+// which glitches the start, so they get a longer look. lowLatency turns
+// off ffplay's input buffering, and shortens the probe for the formats
+// that can take it.
+func formatArgs(f streamFormat, lowLatency bool) []string {
+	var args []string
+	switch {
+	case f.Codec == "opus" || f.Codec == "vorbis" || strings.Contains(f.Container, "ogg"):
+		args = append(args, "-probesize", "10M", "-analyzeduration", "10000000")
+	case lowLatency:
+		args = append(args, "-probesize", "32768", "-analyzeduration", "500000")
+	}
+	if lowLatency {
+		args = append(args, "-fflags", "nobuffer", "-flags", "low_delay")
+	}
+	return args
+}
@@ -0,0 +1,91 @@
+package radio
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// StationAuth holds HTTP Basic credentials for a protected stream
+type StationAuth struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// requestHeaders returns the extra HTTP headers to send for the station:
+// its configured headers plus Authorization when it has credentials
+func (s Station) requestHeaders() http.Header {
+	if len(s.Headers) == 0 && s.Auth == nil {
+		return nil
+	}
+	h := http.Header{}
+	for k, v := range s.Headers {
+		h.Set(k, v)
+	}
+	if s.Auth != nil {
+		req := http.Request{Header: h}
+		req.SetBasicAuth(s.Auth.Username, s.Auth.Password)
+	}
+	return h
+}
+
+// ValidateHeaders drops headers that can't be sent safely: invalid names
+// and values with line breaks, which would inject extra headers into
+// ffplay's -headers block. It returns a warning for each one dropped.
+func ValidateHeaders(headers map[string]string) []string {
+	var warnings []string
+	for k, v := range headers {
+		switch {
+		case k == "" || strings.ContainsAny(k, " \t\r\n:"):
+			warnings = append(warnings, fmt.Sprintf("invalid header name %q; ignored", k))
+		case strings.ContainsAny(v, "\r\n"):
+			warnings = append(warnings, fmt.Sprintf("header %s contains a line break; ignored", k))
+		default:
+			continue
+		}
+		delete(headers, k)
+	}
+	sort.Strings(warnings)
+	return warnings
+}
+
+// addHeaders sets the extra headers on an outgoing request
+func addHeaders(req *http.Request, h http.Header) {
+	for k, values := range h {
+		req.Header[k] = append([]string(nil), values...)
+	}
+}
+
+// headerLines returns the headers as sorted "Name: value" lines
+func headerLines(h http.Header) []string {
+	var lines []string
+	for k, values := range h {
+		for _, v := range values {
+			lines = append(lines, k+": "+v)
+		}
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+// ffmpegHeaders formats headers for ffplay/ffprobe's -headers option,
+// which takes CRLF-terminated header lines
+func ffmpegHeaders(h http.Header) string {
+	var b strings.Builder
+	for _, line := range headerLines(h) {
+		b.WriteString(line)
+		b.WriteString("\r\n")
+	}
+	return b.String()
+}
+
+// RedactURL hides a password embedded in a URL so it can be displayed
+func RedactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return raw
+	}
+	return u.Redacted()
+}
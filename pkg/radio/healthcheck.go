@@ -0,0 +1,120 @@
+package radio
+
+import (
+	"context"
+	"net/http"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	checkWorkers     = 4
+	checkHTTPTimeout = 10 * time.Second
+	checkYTTimeout   = 30 * time.Second
+)
+
+// StationCheck is the result of probing one station
+type StationCheck struct {
+	Station Station
+	OK      bool
+	Status  string
+	Latency time.Duration
+}
+
+// CheckStations probes every station concurrently with a bounded worker
+// pool. Results come back in station order.
+func CheckStations(stations []Station, client *http.Client, opts ResolveOptions) []StationCheck {
+	results := make([]StationCheck, len(stations))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < checkWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = checkStation(stations[i], client, opts)
+			}
+		}()
+	}
+	for i := range stations {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+// checkStation probes a single station: yt-dlp --simulate for page URLs,
+// otherwise an HTTP HEAD (falling back to GET for servers that reject HEAD)
+func checkStation(s Station, client *http.Client, opts ResolveOptions) StationCheck {
+	result := StationCheck{Station: s}
+	start := time.Now()
+	headers := s.requestHeaders()
+	opts.Headers = headers
+
+	if NeedsResolution(s.URL) {
+		ctx, cancel := context.WithTimeout(context.Background(), checkYTTimeout)
+		defer cancel()
+		format := opts.Format
+		if format == "" {
+			format = defaultYtdlpFormat
+		}
+		args := []string{"--simulate", "--quiet", "--no-warnings", "-f", format}
+		args = append(args, opts.ytdlpArgs()...)
+		args = append(args, s.URL)
+		cmd := exec.CommandContext(ctx, ytdlpBinary, args...)
+		cmd.WaitDelay = time.Second // Don't hang on grandchildren holding the pipe
+		out, err := cmd.CombinedOutput()
+		result.Latency = time.Since(start)
+		if err != nil {
+			result.Status = firstLine(string(out))
+			if exp, ok := ytdlpExplanations[classifyYtdlpError(string(out))]; ok {
+				result.Status = exp[0]
+			}
+			if result.Status == "" {
+				result.Status = err.Error()
+			}
+			return result
+		}
+		result.OK = true
+		result.Status = "yt-dlp ok"
+		return result
+	}
+
+	resp, err := probeHTTP(client, http.MethodHead, s.URL, headers)
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+		resp, err = probeHTTP(client, http.MethodGet, s.URL, headers)
+	}
+	result.Latency = time.Since(start)
+	if err != nil {
+		result.Status = err.Error()
+		return result
+	}
+	result.OK = resp.StatusCode < 400
+	result.Status = resp.Status
+	return result
+}
+
+// probeHTTP sends one request and closes the body without reading the stream
+func probeHTTP(client *http.Client, method, url string, headers http.Header) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), checkHTTPTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	addHeaders(req, headers)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp, nil
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(line)
+}
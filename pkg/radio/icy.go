@@ -0,0 +1,101 @@
+package radio
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// SetTitleHandler registers a callback for stream title changes. Titles
+// come from Icecast/SHOUTcast (ICY) metadata, which is interleaved with the
+// audio, so watching them means reading a second copy of the stream; the
+// analyzer only does so while a handler is registered.
+func (sa *StreamAnalyzer) SetTitleHandler(fn func(title string)) {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	sa.onTitle = fn
+}
+
+// monitorTitle reads the stream with ICY metadata enabled and reports each
+// new StreamTitle. It returns quietly for streams without ICY metadata.
+func (sa *StreamAnalyzer) monitorTitle(ctx context.Context, url string) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return
+	}
+	sa.mu.RLock()
+	addHeaders(req, sa.headers)
+	sa.mu.RUnlock()
+	req.Header.Set("Icy-MetaData", "1")
+
+	// The stream never ends, so this request can't use the client's timeout
+	client := *sa.client
+	client.Timeout = 0
+	resp, err := client.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	metaInt, err := strconv.Atoi(resp.Header.Get("icy-metaint"))
+	if err != nil || metaInt <= 0 {
+		return
+	}
+
+	r := bufio.NewReader(resp.Body)
+	lastTitle := ""
+	for {
+		// Audio block, then one length byte (in 16-byte units), then metadata
+		if _, err := io.CopyN(io.Discard, r, int64(metaInt)); err != nil {
+			return
+		}
+		lengthByte, err := r.ReadByte()
+		if err != nil {
+			return
+		}
+		if lengthByte == 0 {
+			continue
+		}
+		meta := make([]byte, int(lengthByte)*16)
+		if _, err := io.ReadFull(r, meta); err != nil {
+			return
+		}
+
+		title, ok := parseStreamTitle(string(meta))
+		if !ok || title == lastTitle {
+			continue
+		}
+		lastTitle = title
+		sa.updateStats(func(s *StreamStats) {
+			s.Title = title
+		})
+		sa.mu.RLock()
+		onTitle := sa.onTitle
+		sa.mu.RUnlock()
+		if onTitle != nil {
+			onTitle(title)
+		}
+	}
+}
+
+// parseStreamTitle extracts StreamTitle from an ICY metadata block such as
+// "StreamTitle='Artist - Title';StreamUrl='http://example.com';"
+func parseStreamTitle(meta string) (string, bool) {
+	const key = "StreamTitle='"
+	start := strings.Index(meta, key)
+	if start < 0 {
+		return "", false
+	}
+	rest := meta[start+len(key):]
+	end := strings.Index(rest, "';")
+	if end < 0 {
+		end = strings.LastIndex(rest, "'")
+	}
+	if end < 0 {
+		return "", false
+	}
+	return strings.TrimSpace(rest[:end]), true
+}
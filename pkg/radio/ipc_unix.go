@@ -0,0 +1,15 @@
+//go:build !windows
+
+package radio
+
+import (
+	"net"
+	"time"
+)
+
+const ipcUsesNamedPipes = false
+
+// DialIPC connects to mpv's IPC server, a Unix domain socket
+func DialIPC(path string, timeout time.Duration) (net.Conn, error) {
+	return net.DialTimeout("unix", path, timeout)
+}
@@ -0,0 +1,18 @@
+//go:build windows
+
+package radio
+
+import (
+	"errors"
+	"net"
+	"time"
+)
+
+const ipcUsesNamedPipes = true
+
+// DialIPC would connect to mpv's named pipe. Named pipes aren't reachable
+// through the standard library, so live control is unavailable on Windows
+// and callers fall back to restarting the stream.
+func DialIPC(path string, timeout time.Duration) (net.Conn, error) {
+	return nil, errors.New("mpv IPC is not supported on Windows")
+}
@@ -0,0 +1,34 @@
+package radio
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// IP versions for SetIPVersion
+const (
+	IPAny = 0 // whichever the system prefers
+	IPv4  = 4
+	IPv6  = 6
+)
+
+// dialNetwork returns the network to dial for an IP version
+func dialNetwork(version int) string {
+	switch version {
+	case IPv4:
+		return "tcp4"
+	case IPv6:
+		return "tcp6"
+	}
+	return "tcp"
+}
+
+// dialerFor returns a DialContext that only connects over network,
+// otherwise like http.DefaultTransport's
+func dialerFor(network string) func(ctx context.Context, _, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	return func(ctx context.Context, _, addr string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, addr)
+	}
+}
@@ -0,0 +1,19 @@
+package radio
+
+import "os"
+
+// loopingLocked reports whether the current station should repeat: with
+// WithLoop, or when the station asks for it. Callers hold p.mu.
+func (p *Player) loopingLocked() bool {
+	return p.loop || p.stationLoop
+}
+
+// finiteLocked reports whether resolved is a finite source, such as a file
+// or a short loop, rather than a live stream: a local file, or a stream
+// ffprobe gives a duration for. Callers hold p.mu.
+func (p *Player) finiteLocked(resolved string) bool {
+	if info, err := os.Stat(resolved); err == nil {
+		return !info.IsDir()
+	}
+	return p.formatLocked(resolved).Duration > 0
+}
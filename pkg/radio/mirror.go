@@ -0,0 +1,73 @@
+package radio
+
+import (
+	"slices"
+	"time"
+)
+
+// mirrorSettle is how long a mirror must play to count as working. When
+// it drops after that, the failover starts a fresh round through the
+// mirrors rather than counting it with earlier failures.
+const mirrorSettle = 10 * time.Second
+
+// StreamURLs returns the station's stream URLs in the order they're
+// tried: URL, then its mirrors in URLs
+func (s Station) StreamURLs() []string {
+	urls := make([]string, 0, 1+len(s.URLs))
+	if s.URL != "" {
+		urls = append(urls, s.URL)
+	}
+	for _, u := range s.URLs {
+		if u != "" && !slices.Contains(urls, u) {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+// setMirrorsLocked takes on a station's stream URLs. The mirror in use is
+// kept when it's the same station, so picking it again stays on the
+// mirror that works. Callers hold p.mu.
+func (p *Player) setMirrorsLocked(urls []string) {
+	if slices.Equal(urls, p.mirrors) {
+		return
+	}
+	p.mirrors, p.mirror, p.mirrorFails = urls, 0, 0
+}
+
+// mirrorLocked returns the URL to play for station: the mirror in use
+// when station is the current station's primary URL, station itself
+// otherwise. Callers hold p.mu.
+func (p *Player) mirrorLocked(station string) string {
+	if len(p.mirrors) < 2 || station != p.mirrors[0] {
+		return station
+	}
+	return p.mirrors[p.mirror]
+}
+
+// nextMirrorLocked moves station on to its next mirror after the one in
+// use failed, wrapping around, and reports whether there is one left to
+// try. Once every mirror has failed in a row it gives up and goes back to
+// the primary for the next Start. Callers hold p.mu.
+func (p *Player) nextMirrorLocked(station string) bool {
+	if len(p.mirrors) < 2 || station != p.mirrors[0] {
+		return false
+	}
+	p.mirrorFails++
+	if p.mirrorFails >= len(p.mirrors) {
+		p.mirror, p.mirrorFails = 0, 0
+		return false
+	}
+	p.mirror = (p.mirror + 1) % len(p.mirrors)
+	p.log().Warn("stream failed; trying the next mirror", "mirror", p.mirror+1, "of", len(p.mirrors), "url", RedactURL(p.mirrors[p.mirror]))
+	return true
+}
+
+// droppedLocked is nextMirrorLocked for a stream whose player exited on
+// its own after playing for played. Callers hold p.mu.
+func (p *Player) droppedLocked(station string, played time.Duration) bool {
+	if played >= mirrorSettle {
+		p.mirrorFails = 0
+	}
+	return p.nextMirrorLocked(station)
+}
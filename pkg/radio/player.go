@@ -0,0 +1,1056 @@
+package radio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Station is a stream the player can tune to
+type Station struct {
+	Name        string            `json:"name"`
+	URL         string            `json:"url"`
+	Description string            `json:"description,omitempty"`
+	Volume      *int              `json:"volume,omitempty"`  // overrides the global volume for this station
+	Headers     map[string]string `json:"headers,omitempty"` // extra HTTP headers, e.g. Referer or User-Agent
+	Auth        *StationAuth      `json:"auth,omitempty"`    // HTTP Basic credentials
+	Loop        bool              `json:"loop,omitempty"`    // repeat a finite source, such as a short ambient file
+	URLs        []string          `json:"urls,omitempty"`    // mirrors tried in order when URL fails to start or drops
+	Artwork     string            `json:"artwork,omitempty"` // image URL or file shown with -art
+}
+
+// Player runs one stream at a time through ffplay or mpv, with a
+// StreamAnalyzer watching it
+type Player struct {
+	mu            sync.Mutex
+	cmd           *exec.Cmd
+	exited        chan struct{} // closed when cmd has been waited for
+	gen           uint64        // bumped by every start and stop; see startNext
+	volumePercent int
+	isStopped     bool
+	paused        bool // stopped by Pause; Resume reuses the resolved URL
+	restarting    bool // in Restart; OnChange waits for the outcome
+	volumeCurve   string
+	loudnorm      float64 // loudness normalization target in LUFS; 0 is off
+	eqPreset      string
+	mono          bool // downmix to one channel
+	fade          time.Duration
+	quality       string
+	autoQuality   string        // adaptive downgrade below quality; "" when none
+	rampIn        time.Duration // one-shot fade-in for the next Start (alarm)
+	cutIn         bool          // Restart is starting the same station again, without a fade-in
+	fadeAbort     chan struct{}
+	fadeDone      chan struct{} // closed when Stop's fade-out is over
+	proxy         *url.URL
+	extraArgs     []string
+	reconnect     bool
+	backend       string
+	device        string // audio output device; "" uses the system default
+	detach        bool   // players start in their own session; see Detach
+	ipcPath       string
+	resolvedURL   string
+	preparedURL   string    // station Prepare resolved for the next Start
+	playingURL    string    // station URL last started, to tell restarts from switches
+	mirrors       []string  // the current station's StreamURLs; see SetStation
+	mirror        int       // index in mirrors of the one in use
+	mirrorFails   int       // mirrors that failed in a row
+	stationStart  time.Time // when the current station started playing
+	stationName   string
+	playlist      []string  // tracks of a local directory station; nil for streams
+	track         int       // index of the playing track in playlist
+	trackStart    time.Time // when the playing track started
+	quickExits    int       // tracks in a row that ended right away
+	shuffle       bool      // play directory stations in random order
+	loop          bool      // repeat finite sources and start directories over
+	stationLoop   bool      // the current station asks to repeat
+	loopFile      bool      // the player repeats the playing source itself
+	resolve       ResolveOptions
+	headers       http.Header // extra HTTP headers for the current station
+	analyzer      *StreamAnalyzer
+	analyze       bool
+	onChange      func(PlayerStatus)
+	logger        *slog.Logger    // nil logs to slog.Default()
+	connectWait   time.Duration   // give up on streams that don't answer in time; 0 waits forever
+	onUnreachable func(string)    // called with the station when connectWait runs out
+	dryRun        io.Writer       // prints commands instead of running them
+	dryRunResolve bool            // still resolves page URLs in a dry run
+	ctx           context.Context // parent for stream analysis; see SetContext
+
+	format     streamFormat // ffprobe's answer for formatURL
+	formatURL  string
+	lowLatency bool // cut ffplay's input buffering; see WithLowLatency
+
+	pcmOut io.Writer // where BackendPCM writes its audio; see WithPCM
+
+	prebuffer time.Duration // audio buffered before playback starts; see WithPrebuffer
+	boost     bool          // volumes up to MaxBoostVolume; see WithVolumeBoost
+
+	quiet bool // ffplay and ffmpeg print errors only; see WithQuiet
+}
+
+// Option configures a Player in NewPlayer
+type Option func(*Player)
+
+// WithBackend picks the player binary, BackendFFplay (the default) or
+// BackendMPV
+func WithBackend(backend string) Option {
+	return func(p *Player) { p.backend = backend }
+}
+
+// WithPCM decodes stations to raw PCM on w (see PCMSampleRate and
+// PCMChannels) with ffmpeg, instead of playing them, for piping into
+// another program. Stop gives ffmpeg time to flush what it has decoded.
+func WithPCM(w io.Writer) Option {
+	return func(p *Player) {
+		p.backend = BackendPCM
+		p.pcmOut = w
+	}
+}
+
+// WithPrebuffer buffers d of audio before a stream starts playing, so a
+// flaky connection has a cushion to ride out stalls. mpv does it with its
+// cache; ffplay plays remote streams through an ffmpeg decoder and a
+// buffer in this process instead, which isn't done for looped or
+// detached streams. BackendPCM's reader does its own buffering.
+func WithPrebuffer(d time.Duration) Option {
+	return func(p *Player) { p.prebuffer = d }
+}
+
+// WithVolumeBoost lets SetVolume go past 100%, up to MaxBoostVolume, for
+// streams that are too quiet even at full volume. The excess is gain
+// (see VolumeBoostDB), which can clip loud passages.
+func WithVolumeBoost() Option {
+	return func(p *Player) { p.boost = true }
+}
+
+// WithVolumeCurve picks how ffplay maps volume percentages to gain: CurveDB
+// (the default), CurvePerceptual, or CurveLinear. See ParseVolumeCurve.
+func WithVolumeCurve(curve string) Option {
+	return func(p *Player) { p.volumeCurve = curve }
+}
+
+// WithAnalyzer turns stream analysis on (the default) or off. Off, the
+// analyzer's stats stay empty and no probe requests are made.
+func WithAnalyzer(enabled bool) Option {
+	return func(p *Player) { p.analyze = enabled }
+}
+
+// WithReconnect sets whether ffplay reconnects dropped http(s) streams
+// (the default) or gives up
+func WithReconnect(enabled bool) Option {
+	return func(p *Player) { p.reconnect = enabled }
+}
+
+// WithDevice plays through the named audio output device instead of the
+// system default. See ListAudioDevices.
+func WithDevice(name string) Option {
+	return func(p *Player) { p.device = name }
+}
+
+// WithShuffle plays the files of a directory station in random order
+// instead of by name
+func WithShuffle(enabled bool) Option {
+	return func(p *Player) { p.shuffle = enabled }
+}
+
+// WithLoop repeats finite sources, such as files and short loops, without
+// a gap, and starts directory stations over when they end. Live streams
+// aren't affected. Station.Loop does the same for one station.
+func WithLoop(enabled bool) Option {
+	return func(p *Player) { p.loop = enabled }
+}
+
+// WithDetach starts each player in a session of its own, away from the
+// terminal, so Detach can leave it playing after the program exits
+func WithDetach() Option {
+	return func(p *Player) { p.detach = true }
+}
+
+// WithLowLatency starts streams with as little buffering as the player
+// allows, trading resilience to network hiccups for a shorter delay
+func WithLowLatency() Option {
+	return func(p *Player) { p.lowLatency = true }
+}
+
+// WithQuiet has ffplay and ffmpeg print only errors, not warnings. mpv
+// prints nothing either way.
+func WithQuiet() Option {
+	return func(p *Player) { p.quiet = true }
+}
+
+// WithConnectTimeout stops a stream whose server hasn't answered within d
+// of Start, so a dead station doesn't sit loading forever; see
+// OnUnreachable. The analyzer's probes tell whether the server answered,
+// so the timeout only applies to http(s) streams with analysis on. A d of
+// 0 waits forever (the default).
+func WithConnectTimeout(d time.Duration) Option {
+	return func(p *Player) { p.connectWait = d }
+}
+
+// WithLogger sends the player's warnings and debug messages to l instead
+// of slog's default logger
+func WithLogger(l *slog.Logger) Option {
+	return func(p *Player) { p.logger = l }
+}
+
+// WithDryRun makes Start print the player command line, and the yt-dlp
+// command for page URLs, to w instead of playing. With resolve set, yt-dlp
+// still runs so the printed player command has the real media URL;
+// otherwise nothing is run at all.
+func WithDryRun(w io.Writer, resolve bool) Option {
+	return func(p *Player) {
+		p.dryRun = w
+		p.dryRunResolve = resolve
+	}
+}
+
+// NewPlayer returns a stopped player at 70% volume
+func NewPlayer(opts ...Option) *Player {
+	p := &Player{
+		volumePercent: 70,
+		volumeCurve:   CurveDB,
+		reconnect:     true,
+		backend:       BackendFFplay,
+		ipcPath:       mpvIPCPath(),
+		analyzer:      NewStreamAnalyzer(),
+		analyze:       true,
+		ctx:           context.Background(),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// SetContext ties background work (stream analysis) to ctx, so cancelling
+// it on quit stops every monitor goroutine
+func (p *Player) SetContext(ctx context.Context) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ctx = ctx
+}
+
+// OnChange registers fn to receive the player's status whenever playback
+// starts or stops or the volume changes. A Restart reports only its
+// outcome. fn is called without the player's lock held; set it before
+// starting playback.
+func (p *Player) OnChange(fn func(PlayerStatus)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onChange = fn
+}
+
+// OnUnreachable registers fn to be called with the station URL when a
+// stream is stopped by the WithConnectTimeout timeout, or for silence
+// with WithSilenceDetect's skip. fn is called without the player's lock
+// held, after the stop has been reported to OnChange.
+func (p *Player) OnUnreachable(fn func(station string)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onUnreachable = fn
+}
+
+// changed reports the current status to the OnChange handler
+func (p *Player) changed() {
+	p.mu.Lock()
+	fn, restarting := p.onChange, p.restarting
+	p.mu.Unlock()
+	if fn != nil && !restarting {
+		fn(p.Status())
+	}
+}
+
+// log returns the logger for the player's diagnostics
+func (p *Player) log() *slog.Logger {
+	if p.logger != nil {
+		return p.logger
+	}
+	return slog.Default()
+}
+
+// Analyzer returns the player's stream analyzer
+func (p *Player) Analyzer() *StreamAnalyzer {
+	return p.analyzer
+}
+
+// Backend returns the player binary in use
+func (p *Player) Backend() string {
+	return p.backend
+}
+
+func (p *Player) ffplayArgs(url string, format streamFormat) []string {
+	// ffplay volume is applied with an -af volume filter; the curve decides
+	// how 0-100% maps onto it
+	filters := p.audioFilters(volumeFilter(p.volumePercent, p.volumeCurve))
+	args := []string{
+		"-nodisp",
+		"-autoexit",
+		"-loglevel", p.ffmpegLogLevel(), // Warnings for audio processing, or errors only
+		"-hide_banner", // Hide ffplay banner
+		"-af", strings.Join(filters, ","),
+	}
+	// Let ffmpeg's HTTP protocol ride out dropped connections. These options
+	// are rejected for other protocols, so only add them for http(s).
+	if p.reconnect && isHTTPURL(url) {
+		args = append(args, "-reconnect", "1", "-reconnect_streamed", "1", "-reconnect_delay_max", "5")
+	}
+	if len(p.headers) > 0 && isHTTPURL(url) {
+		args = append(args, "-headers", ffmpegHeaders(p.headers))
+	}
+	if p.loopFile {
+		args = append(args, "-loop", "0")
+	}
+	if format.Video {
+		// A combined audio/video stream, as yt-dlp picks when there's no
+		// audio-only format: keep ffplay from demuxing the video at all
+		args = append(args, "-vn")
+	}
+	args = append(args, formatArgs(format, p.lowLatency)...)
+	// User extras go last so they can tune input options; the URL must stay
+	// the final argument
+	args = append(args, p.extraArgs...)
+	return append(args, url)
+}
+
+// ErrAlreadyPlaying is returned by Start while a stream is playing; Restart
+// replaces it instead
+var ErrAlreadyPlaying = errors.New("player already running")
+
+// Start resolves url and starts playing it. It fails with
+// ErrAlreadyPlaying if a stream is already playing. A local directory
+// plays its audio files one after another; starting the same station
+// again picks up at the current track.
+func (p *Player) Start(url string) error {
+	defer p.changed()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.runningLocked() {
+		return ErrAlreadyPlaying
+	}
+	return p.startLocked(url)
+}
+
+// runningLocked reports whether a player process is running. One that has
+// exited but hasn't been cleaned up by its Start goroutine yet no longer
+// counts; it's cleared here, so a Start right after a stream ends doesn't
+// fail. Callers hold p.mu.
+func (p *Player) runningLocked() bool {
+	if p.cmd == nil || p.cmd.Process == nil {
+		return false
+	}
+	select {
+	case <-p.exited:
+		p.cmd = nil
+		return false
+	default:
+		return true
+	}
+}
+
+// startLocked does the work of Start once nothing is running. Callers
+// hold p.mu and report the change.
+func (p *Player) startLocked(url string) error {
+	station := url
+	if station != p.playingURL || p.playlist == nil {
+		if err := p.loadPlaylistLocked(station); err != nil {
+			return err
+		}
+	}
+	if len(p.playlist) > 0 {
+		url = p.playlist[p.track]
+	} else {
+		url = p.mirrorLocked(station)
+	}
+	resolved, err := p.prepareLocked(url)
+	for err != nil && p.nextMirrorLocked(station) {
+		url = p.mirrors[p.mirror]
+		resolved, err = p.prepareLocked(url)
+	}
+	if err != nil {
+		return err
+	}
+	// A directory's tracks loop as a playlist; a single finite source is
+	// looped by the player, so there's no gap between repeats
+	p.loopFile = len(p.playlist) == 0 && p.loopingLocked() && p.finiteLocked(resolved)
+
+	var args, decoderArgs []string
+	switch {
+	case p.backend == BackendMPV:
+		args = p.mpvArgs(resolved)
+	case p.backend == BackendPCM:
+		args = p.pcmArgs(resolved)
+	case p.prerollingLocked(resolved):
+		// ffmpeg decodes the stream into the pre-roll buffer, and ffplay
+		// plays from it once it's full
+		decoderArgs = p.decoderArgs(resolved, false, nil, "wav")
+		args = p.ffplayArgs("pipe:0", streamFormat{})
+	default:
+		// Tune ffplay's probing to the stream; mpv probes well on its own
+		var format streamFormat
+		if isHTTPURL(resolved) {
+			format = p.formatLocked(resolved)
+		}
+		if format.NoAudio {
+			// ffplay would open a video-only or text stream and then fail
+			// in its own confusing way
+			p.analyzer.StopAnalysis()
+			return ErrNoAudio
+		}
+		args = p.ffplayArgs(resolved, format)
+	}
+	p.rampIn = 0
+	p.analyzer.SetPreroll(p.prerollLocked(resolved))
+	if p.dryRun != nil {
+		line := commandLine(p.playerEnvVars(), append([]string{p.backend}, args...))
+		if decoderArgs != nil {
+			line = commandLine(p.playerEnvVars(), append([]string{BackendPCM}, decoderArgs...)) + " | " + line
+		}
+		fmt.Fprintln(p.dryRun, line)
+		return nil
+	}
+	var decoder *exec.Cmd
+	var decoded *os.File
+	if decoderArgs != nil {
+		if decoder, decoded, err = p.startPreroll(decoderArgs); err != nil {
+			return err
+		}
+		// The player has its own copy once started
+		defer decoded.Close()
+	}
+	p.cmd = exec.Command(p.backend, args...)
+	p.cmd.Env = p.playerEnv()
+	p.cmd.Stdout = os.Stdout
+	if p.pcmOut != nil {
+		p.cmd.Stdout = p.pcmOut
+	}
+	if decoded != nil {
+		p.cmd.Stdin = decoded
+	}
+	p.cmd.Stderr = os.Stderr
+	if p.detach {
+		p.cmd.SysProcAttr = detachedProcAttr()
+	}
+	p.log().Debug("starting player", "backend", p.backend, "url", RedactURL(resolved))
+	if err := p.cmd.Start(); err != nil {
+		p.cmd = nil
+		if decoder != nil {
+			_ = decoder.Process.Kill()
+		}
+		return err
+	}
+	if decoder != nil {
+		// The decoder does the downloading
+		p.analyzer.TrackProcess(decoder.Process.Pid)
+	} else {
+		p.analyzer.TrackProcess(p.cmd.Process.Pid)
+	}
+	p.gen++
+	p.isStopped = false
+	p.trackStart = time.Now()
+	// Volume and EQ changes restart the same stream; only a new station
+	// resets the playing-for clock
+	if station != p.playingURL || p.stationStart.IsZero() {
+		p.playingURL = station
+		p.stationStart = time.Now()
+	}
+	exited := make(chan struct{})
+	p.exited = exited
+	if p.connectWait > 0 && p.analyze && isHTTPURL(resolved) {
+		go p.watchConnect(p.cmd, station, exited, p.analyzer.Connected(), p.connectWait)
+	}
+	go func(cmd *exec.Cmd) {
+		err := cmd.Wait()
+		if decoder != nil {
+			_ = decoder.Process.Kill()
+		}
+		close(exited)
+		p.log().Debug("player exited", "pid", cmd.Process.Pid, "err", err)
+		p.mu.Lock()
+		// Exited on its own (stream ended or failed), not via Stop
+		own := p.cmd == cmd
+		next := false
+		if own {
+			p.cmd = nil
+			played := time.Since(p.trackStart)
+			// A looping source whose player quit anyway starts over, unless
+			// it can't play at all. A stream with mirrors moves on to the
+			// next one.
+			next = p.nextTrackLocked(played) || (p.loopFile && played >= minTrackPlay) || p.droppedLocked(p.playingURL, played)
+		}
+		station, gen := p.playingURL, p.gen
+		p.mu.Unlock()
+		switch {
+		case next:
+			p.startNext(station, gen)
+		case own:
+			p.changed()
+		}
+	}(p.cmd)
+	return nil
+}
+
+// startNext starts the next track of station after the last one ended,
+// unless a start or stop since gen has taken over. The change reports the
+// outcome, playing or stopped.
+func (p *Player) startNext(station string, gen uint64) {
+	defer p.changed()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.gen != gen {
+		return
+	}
+	_ = p.startLocked(station)
+}
+
+// watchConnect stops cmd if the analyzer hasn't heard from the stream's
+// server within timeout, unless cmd has exited or been replaced by then
+func (p *Player) watchConnect(cmd *exec.Cmd, station string, exited, connected <-chan struct{}, timeout time.Duration) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-connected:
+		return
+	case <-exited:
+		return
+	case <-timer.C:
+	}
+	p.streamFailed(cmd, station, "stream didn't connect in time")
+}
+
+// streamFailed gives up on cmd playing station, for why, unless it has
+// been replaced by then: the station moves on to its next mirror, or
+// stops and goes to the OnUnreachable handler
+func (p *Player) streamFailed(cmd *exec.Cmd, station, why string) {
+	p.mu.Lock()
+	current, fn := p.cmd == cmd, p.onUnreachable
+	if !current {
+		p.mu.Unlock()
+		return
+	}
+	p.log().Debug(why, "station", RedactURL(station))
+	if p.nextMirrorLocked(station) {
+		// Move on to the mirror as one change, without a stop in between
+		_ = p.stopLocked()
+		_ = p.startLocked(station)
+		p.mu.Unlock()
+		p.changed()
+		return
+	}
+	p.mu.Unlock()
+	_ = p.StopNow()
+	if fn != nil {
+		fn(station)
+	}
+}
+
+// Prepare resolves url and starts stream analysis without starting the
+// player, leaving it ready: the next Start of that station skips the
+// yt-dlp delay and begins playing right away
+func (p *Player) Prepare(url string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.runningLocked() {
+		return ErrAlreadyPlaying
+	}
+	url = p.mirrorLocked(url)
+	if _, err := p.prepareLocked(url); err != nil {
+		return err
+	}
+	p.preparedURL = url
+	p.isStopped = true
+	return nil
+}
+
+// prepareLocked resolves url and starts analysing the stream, unless
+// Prepare already did both for this station. Callers hold p.mu.
+func (p *Player) prepareLocked(url string) (string, error) {
+	if p.preparedURL != "" && p.preparedURL == url {
+		p.preparedURL = ""
+		if p.paused {
+			// Pause stopped the analysis along with the player
+			p.paused = false
+			p.startAnalysisLocked(url, p.resolvedURL)
+		}
+		return p.resolvedURL, nil
+	}
+	p.preparedURL = ""
+	p.paused = false
+	opts := p.resolve
+	if p.autoQuality != "" {
+		opts.Format, _ = QualityFormat(p.autoQuality)
+	}
+	if p.dryRun != nil {
+		opts.Trace = p.dryRun
+		if !p.dryRunResolve && NeedsResolution(url) {
+			fmt.Fprintln(p.dryRun, commandLine(nil, ytdlpCommand(url, opts)))
+			return url, nil
+		}
+	}
+	resolved, err := ResolvePlayableURL(url, opts)
+	if err != nil {
+		return "", err
+	}
+	if resolved != url {
+		p.log().Debug("resolved stream", "url", RedactURL(url), "media", RedactURL(resolved))
+	}
+
+	p.resolvedURL = resolved
+	switch {
+	case !NeedsResolution(url):
+		p.analyzer.SetQuality("")
+	case p.autoQuality != "":
+		p.analyzer.SetQuality(p.autoQuality + " (adaptive)")
+	default:
+		p.analyzer.SetQuality(p.quality)
+	}
+	p.startAnalysisLocked(url, resolved)
+	return resolved, nil
+}
+
+// startAnalysisLocked starts analysing resolved, the media URL of url.
+// Callers hold p.mu.
+func (p *Player) startAnalysisLocked(url, resolved string) {
+	if !p.analyze || p.dryRun != nil {
+		return
+	}
+	p.analyzer.SetSource(url)
+	if err := p.analyzer.StartAnalysis(p.ctx, resolved); err != nil {
+		// Don't fail the entire start if analysis fails
+		p.log().Warn("could not start stream analysis", "err", err)
+	}
+}
+
+// Stop fades out the current stream (when a fade is configured) and then
+// terminates it.
+func (p *Player) Stop() error {
+	p.mu.Lock()
+	var proc *os.Process
+	if p.cmd != nil && p.cmd.Process != nil {
+		proc = p.cmd.Process
+	}
+	fade := p.fade
+	abort, done := make(chan struct{}), make(chan struct{})
+	p.fadeAbort, p.fadeDone = abort, done
+	p.mu.Unlock()
+
+	if proc != nil {
+		fadeOut(proc, fade, abort)
+	}
+	close(done)
+	return p.stopProcess()
+}
+
+// StopNow terminates the stream immediately, cutting short any fade-out
+// already in progress. It waits for that fade to put the stream's volume
+// back first.
+func (p *Player) StopNow() error {
+	p.mu.Lock()
+	done := p.fadeDone
+	if p.fadeAbort != nil {
+		close(p.fadeAbort)
+		p.fadeAbort = nil
+	}
+	p.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+	return p.stopProcess()
+}
+
+func (p *Player) stopProcess() error {
+	defer p.changed()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stopLocked()
+}
+
+// stopLocked terminates the player process, if any, and waits for it to
+// exit. Callers hold p.mu and report the change.
+func (p *Player) stopLocked() error {
+	p.gen++
+	if p.cmd == nil || p.cmd.Process == nil {
+		p.isStopped = true
+		p.preparedURL = ""
+		p.paused = false
+		p.analyzer.StopAnalysis()
+		return nil
+	}
+	p.isStopped = true
+
+	// Stop stream analysis
+	p.analyzer.StopAnalysis()
+
+	// Send SIGTERM to stop the process. Where that can't be sent (Windows),
+	// or the process is already gone, kill it.
+	var err error
+	if p.cmd.Process.Signal(syscall.SIGTERM) == nil {
+		// A fade-out may have frozen it
+		thawProcess(p.cmd.Process)
+	} else if err = p.cmd.Process.Kill(); errors.Is(err, os.ErrProcessDone) {
+		err = nil
+	}
+
+	// Wait for the process to actually exit. The goroutine started in
+	// Start owns the Wait call; it closes exited once the process is gone.
+	select {
+	case <-p.exited:
+	case <-time.After(2 * time.Second):
+		// Force kill if it doesn't exit within 2 seconds
+		_ = p.cmd.Process.Kill()
+		<-p.exited
+	}
+	// Cleared whatever the signals did; a p.cmd left set would fail every
+	// later Start with ErrAlreadyPlaying
+	p.cmd = nil
+	return err
+}
+
+// Pause stops the stream but keeps its resolved media URL, so Resume
+// starts it again without another yt-dlp lookup. Live streams can't be
+// time-shifted, so Resume joins the broadcast where it is by then.
+func (p *Player) Pause() error {
+	p.mu.Lock()
+	url := p.mirrorLocked(p.playingURL)
+	playing := p.cmd != nil && !p.isStopped
+	p.mu.Unlock()
+	if !playing {
+		return errors.New("not playing")
+	}
+	if err := p.Stop(); err != nil {
+		return err
+	}
+	defer p.changed()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.preparedURL = url
+	p.paused = true
+	return nil
+}
+
+// Resume restarts the stream Pause stopped
+func (p *Player) Resume() error {
+	p.mu.Lock()
+	url, paused := p.playingURL, p.paused
+	p.mu.Unlock()
+	if !paused {
+		return errors.New("not paused")
+	}
+	return p.Start(url)
+}
+
+// PlayerStatus is a snapshot of the player's settings and state
+type PlayerStatus struct {
+	Name        string // station name from SetStation
+	Track       string // file name of the playing track of a directory station
+	Playing     bool
+	Paused      bool // stopped by Pause
+	Ready       bool // resolved by Prepare, waiting for play
+	Volume      int
+	EQ          string
+	Mono        bool // downmixed to one channel
+	Quality     string
+	Backend     string
+	Device      string
+	ResolvedURL string
+	Since       time.Time // when the current station started playing
+	Mirror      int       // index in the station's StreamURLs of the one in use
+	Mirrors     int       // how many stream URLs the station has
+}
+
+// Status returns the player's current state, read under the lock so it's
+// consistent with the process lifecycle goroutines
+func (p *Player) Status() PlayerStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return PlayerStatus{
+		Name:        p.stationName,
+		Track:       p.trackName(),
+		Playing:     p.cmd != nil && !p.isStopped,
+		Paused:      p.paused,
+		Ready:       p.cmd == nil && p.preparedURL != "" && !p.paused,
+		Volume:      p.volumePercent,
+		EQ:          p.eqPreset,
+		Mono:        p.mono,
+		Quality:     p.quality,
+		Backend:     p.backend,
+		Device:      p.device,
+		ResolvedURL: p.resolvedURL,
+		Since:       p.stationStart,
+		Mirror:      p.mirror,
+		Mirrors:     len(p.mirrors),
+	}
+}
+
+// trackName returns the playing track's file name, or "" for stations
+// without a playlist. Callers hold p.mu.
+func (p *Player) trackName() string {
+	if len(p.playlist) == 0 {
+		return ""
+	}
+	return filepath.Base(p.playlist[p.track])
+}
+
+// Connected returns a channel that's closed once the server of the stream
+// being played has answered the analyzer's probes. Streams that aren't
+// probed (local files, other protocols, or with analysis off) count as
+// connected right away.
+func (p *Player) Connected() <-chan struct{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.analyze || p.dryRun != nil || !isHTTPURL(p.resolvedURL) {
+		done := make(chan struct{})
+		close(done)
+		return done
+	}
+	return p.analyzer.Connected()
+}
+
+// Playing reports whether a stream is playing
+func (p *Player) Playing() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return !p.isStopped
+}
+
+// Restart stops the stream and starts url, e.g. to apply settings that
+// ffplay can't change while running. A stream started by someone else
+// meanwhile is replaced too, so url is what plays afterwards. Switching to
+// another station fades like Stop and Start; restarting the playing one,
+// for a volume, EQ, or quality change, cuts straight over.
+func (p *Player) Restart(url string) error {
+	p.mu.Lock()
+	p.restarting = true
+	same := url == p.playingURL && p.runningLocked()
+	p.mu.Unlock()
+	if same {
+		_ = p.StopNow()
+	} else {
+		_ = p.Stop()
+	}
+	p.mu.Lock()
+	if p.runningLocked() {
+		_ = p.stopLocked()
+	}
+	p.cutIn = same
+	err := p.startLocked(url)
+	p.cutIn = false
+	p.restarting = false
+	p.mu.Unlock()
+	p.changed()
+	return err
+}
+
+// SetStation takes on a station's name, mirrors, and HTTP headers and
+// credentials for the next Start. The station's volume is left to the
+// caller.
+func (p *Player) SetStation(st Station) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.setMirrorsLocked(st.StreamURLs())
+	p.stationName = st.Name
+	p.stationLoop = st.Loop
+	p.headers = st.requestHeaders()
+	p.resolve.Headers = p.headers
+	p.analyzer.SetHeaders(p.headers)
+}
+
+// SetVolume sets the volume, clamped to 0-MaxVolume. With the mpv backend the
+// change is sent to the running stream over IPC; it reports whether that
+// happened; otherwise the stream must be restarted to hear the change.
+func (p *Player) SetVolume(percent int) bool {
+	percent = max(0, min(p.MaxVolume(), percent))
+	p.mu.Lock()
+	p.volumePercent = percent
+	running := p.cmd != nil && p.cmd.Process != nil
+	p.mu.Unlock()
+	p.changed()
+
+	if !running || p.backend != BackendMPV {
+		return false
+	}
+	return mpvCommand(p.ipcPath, "set_property", "volume", percent) == nil
+}
+
+// MaxVolume returns the highest volume SetVolume allows: 100, or
+// MaxBoostVolume with WithVolumeBoost
+func (p *Player) MaxVolume() int {
+	if p.boost {
+		return MaxBoostVolume
+	}
+	return 100
+}
+
+// Volume returns the volume percentage
+func (p *Player) Volume() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.volumePercent
+}
+
+// SetFade sets the fade-in/fade-out duration, bounded by MaxFade
+func (p *Player) SetFade(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	if d > MaxFade {
+		d = MaxFade
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.fade = d
+}
+
+// SetProxy routes ffplay, yt-dlp, and the stream analyzer through a proxy.
+// A nil URL connects directly.
+func (p *Player) SetProxy(u *url.URL) {
+	p.mu.Lock()
+	p.proxy = u
+	p.resolve.Proxy = ""
+	if u != nil {
+		p.resolve.Proxy = u.String()
+	}
+	p.mu.Unlock()
+	p.analyzer.SetProxy(u)
+}
+
+// SetIPVersion makes yt-dlp and the stream analyzer connect over IPv4 or
+// IPv6 only, or either for IPAny. ffmpeg and mpv have no such setting, so
+// the players and ffprobe still connect the way the system prefers.
+func (p *Player) SetIPVersion(version int) {
+	p.mu.Lock()
+	p.resolve.IPVersion = version
+	p.mu.Unlock()
+	p.analyzer.SetIPVersion(version)
+}
+
+// SetCookies passes a cookies file, or cookies from a browser, to yt-dlp
+func (p *Player) SetCookies(file, fromBrowser string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.resolve.Cookies = file
+	p.resolve.CookiesFromBrowser = fromBrowser
+}
+
+// ResolveOptions returns the yt-dlp settings for the current station
+func (p *Player) ResolveOptions() ResolveOptions {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.resolve
+}
+
+// SetExtraArgs parses user-supplied ffplay arguments. ffplay has no way to
+// turn -nodisp or -autoexit back off, so headless operation can't be broken;
+// a user -af replaces the volume/EQ filter chain since the last -af wins.
+func (p *Player) SetExtraArgs(raw string) error {
+	args, err := splitArgs(raw)
+	if err != nil {
+		return fmt.Errorf("invalid -ffplay-args: %w", err)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.extraArgs = args
+	return nil
+}
+
+// SetEQ selects an EQ preset or custom gains string
+func (p *Player) SetEQ(setting string) error {
+	if _, err := eqFilter(setting); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.eqPreset = strings.ToLower(strings.TrimSpace(setting))
+	return nil
+}
+
+// EQ returns the EQ setting; empty means flat
+func (p *Player) EQ() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.eqPreset
+}
+
+// SetMono turns downmixing to one channel on or off from the next Start,
+// for a single speaker or listening with one ear
+func (p *Player) SetMono(enabled bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.mono = enabled
+}
+
+// Mono reports whether the stream is downmixed to one channel
+func (p *Player) Mono() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.mono
+}
+
+// SetDataCap calls onReached once the session has downloaded capBytes
+func (p *Player) SetDataCap(capBytes int64, onReached func(used int64)) {
+	p.analyzer.SetDataCap(capBytes, onReached)
+}
+
+// SetQuality picks the yt-dlp format for page URLs. Unknown values fall
+// back to the best available audio with a warning.
+func (p *Player) SetQuality(quality string) {
+	format, ok := QualityFormat(quality)
+	if !ok {
+		p.log().Warn("unknown quality, using high (choose low, medium, or high)", "quality", quality, "format", format)
+		quality = "high"
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.quality = strings.ToLower(quality)
+	p.autoQuality = ""
+	p.resolve.Format = format
+}
+
+// Quality returns the quality chosen with SetQuality
+func (p *Player) Quality() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.quality
+}
+
+// SetDevice plays through the named audio output device from the next
+// Start; "" uses the system default
+func (p *Player) SetDevice(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.device = name
+}
+
+// Device returns the audio output device; "" is the system default
+func (p *Player) Device() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.device
+}
+
+// SetRampIn makes the next Start fade in over d instead of the usual fade
+func (p *Player) SetRampIn(d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rampIn = d
+}
+
+// StationElapsed returns how long the current station has been playing
+func (p *Player) StationElapsed() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stationStart.IsZero() {
+		return 0
+	}
+	return time.Since(p.stationStart)
+}
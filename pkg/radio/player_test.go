@@ -0,0 +1,179 @@
+//go:build !windows
+
+package radio
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestPlayer returns a player whose backend is a shell script that
+// plays nothing until it's stopped, and a local file station for it
+func newTestPlayer(t *testing.T, opts ...Option) (*Player, string) {
+	t.Helper()
+	dir := t.TempDir()
+	backend := filepath.Join(dir, "fake-player")
+	if err := os.WriteFile(backend, []byte("#!/bin/sh\nexec sleep 60\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	station := filepath.Join(dir, "station.mp3")
+	if err := os.WriteFile(station, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	opts = append([]Option{WithBackend(backend), WithAnalyzer(false)}, opts...)
+	return NewPlayer(opts...), station
+}
+
+func TestRapidStopStart(t *testing.T) {
+	p, station := newTestPlayer(t)
+	for i := range 20 {
+		if err := p.Start(station); err != nil {
+			t.Fatalf("start %d: %v", i+1, err)
+		}
+		if err := p.Stop(); err != nil {
+			t.Fatalf("stop %d: %v", i+1, err)
+		}
+	}
+}
+
+func TestRapidRestart(t *testing.T) {
+	p, station := newTestPlayer(t)
+	if err := p.Start(station); err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop()
+	for i := range 20 {
+		if err := p.Restart(station); err != nil {
+			t.Fatalf("restart %d: %v", i+1, err)
+		}
+		if err := p.Start(station); !errors.Is(err, ErrAlreadyPlaying) {
+			t.Fatalf("start while playing after restart %d = %v, want ErrAlreadyPlaying", i+1, err)
+		}
+	}
+}
+
+// TestSettersRace changes settings while the player starts and stops and
+// other goroutines read them, as the CLI's command loop does while the
+// stream is switched from other goroutines. It's meant for go test -race.
+func TestSettersRace(t *testing.T) {
+	p, station := newTestPlayer(t)
+	proxy, _ := url.Parse("http://proxy.example:3128")
+	// Enough rounds that the goroutines are preempted and interleave, even
+	// on one CPU
+	const rounds = 20000
+
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	run := func(f func(i int)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			for i := range rounds {
+				f(i)
+			}
+		}()
+	}
+	run(func(i int) { p.SetFade(time.Duration(i%2) * time.Millisecond) })
+	run(func(i int) {
+		if i%2 == 0 {
+			p.SetProxy(proxy)
+		} else {
+			p.SetProxy(nil)
+		}
+	})
+	run(func(i int) { p.SetIPVersion(i % 2 * IPv4) })
+	run(func(int) { p.SetCookies("cookies.txt", "firefox") })
+	run(func(int) { _ = p.SetExtraArgs("-sync audio") })
+	run(func(i int) { _ = p.SetNormalize(-16 - float64(i%2)) })
+	// What Start reads them for
+	run(func(int) {
+		_ = p.ResolveOptions()
+		p.mu.Lock()
+		_ = p.ffplayArgs(station, streamFormat{})
+		_ = p.playerEnvVars()
+		p.mu.Unlock()
+	})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-start
+		for i := range 5 {
+			if err := p.Start(station); err != nil {
+				t.Errorf("start %d: %v", i+1, err)
+				return
+			}
+			if err := p.Stop(); err != nil {
+				t.Errorf("stop %d: %v", i+1, err)
+				return
+			}
+		}
+	}()
+	close(start)
+	wg.Wait()
+}
+
+func TestRestartFadesOnlyOnSwitch(t *testing.T) {
+	dir := t.TempDir()
+	argsFile := filepath.Join(dir, "args")
+	// A player that records its command line
+	backend := filepath.Join(dir, "fake-player")
+	script := "#!/bin/sh\necho \"$@\" > '" + argsFile + "'\nexec sleep 60\n"
+	if err := os.WriteFile(backend, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	var stations []string
+	for _, name := range []string{"one.mp3", "two.mp3"} {
+		station := filepath.Join(dir, name)
+		if err := os.WriteFile(station, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		stations = append(stations, station)
+	}
+	p := NewPlayer(WithBackend(backend), WithAnalyzer(false))
+	p.SetFade(1500 * time.Millisecond)
+	t.Cleanup(func() { _ = p.StopNow() })
+
+	fadesIn := func() bool {
+		t.Helper()
+		// Written as the player starts, which may be just after Start returns
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			args, err := os.ReadFile(argsFile)
+			if err == nil && len(args) > 0 {
+				_ = os.Remove(argsFile)
+				return strings.Contains(string(args), "afade=t=in")
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("the player never started")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	if err := p.Start(stations[0]); err != nil {
+		t.Fatal(err)
+	}
+	if !fadesIn() {
+		t.Error("Start didn't fade in")
+	}
+	// A setting change restarts the same station
+	if err := p.Restart(stations[0]); err != nil {
+		t.Fatal(err)
+	}
+	if fadesIn() {
+		t.Error("restarting the playing station faded in")
+	}
+	if err := p.Restart(stations[1]); err != nil {
+		t.Fatal(err)
+	}
+	if !fadesIn() {
+		t.Error("switching stations didn't fade in")
+	}
+}
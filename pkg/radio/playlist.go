@@ -0,0 +1,96 @@
+package radio
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// audioExtensions are the file types a directory playlist picks up
+var audioExtensions = map[string]bool{
+	".mp3": true, ".ogg": true, ".oga": true, ".opus": true, ".flac": true,
+	".wav": true, ".m4a": true, ".aac": true, ".aiff": true, ".aif": true,
+	".wma": true, ".mka": true, ".webm": true,
+}
+
+// minTrackPlay is how long a track must play to count as played. When
+// every track in a row ends sooner, the files are unplayable and the
+// playlist stops rather than spinning through them.
+const minTrackPlay = time.Second
+
+// dirPlaylist lists the audio files in dir, sorted by name.
+// Subdirectories aren't descended into.
+func dirPlaylist(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var tracks []string
+	for _, e := range entries {
+		if e.IsDir() || !audioExtensions[strings.ToLower(filepath.Ext(e.Name()))] {
+			continue
+		}
+		tracks = append(tracks, filepath.Join(dir, e.Name()))
+	}
+	if len(tracks) == 0 {
+		return nil, fmt.Errorf("no audio files in %s", dir)
+	}
+	return tracks, nil
+}
+
+// loadPlaylistLocked sets up the tracks for a station: every audio file
+// for a directory, and none for single files and streams. Callers hold
+// p.mu.
+func (p *Player) loadPlaylistLocked(station string) error {
+	p.playlist, p.track, p.quickExits = nil, 0, 0
+	info, err := os.Stat(station)
+	if err != nil {
+		// Not a local path
+		return nil
+	}
+	if !info.IsDir() {
+		return nil
+	}
+	tracks, err := dirPlaylist(station)
+	if err != nil {
+		return err
+	}
+	p.playlist = tracks
+	if p.shuffle {
+		rand.Shuffle(len(tracks), func(i, j int) { tracks[i], tracks[j] = tracks[j], tracks[i] })
+	}
+	return nil
+}
+
+// nextTrackLocked moves to the next track after one ends on its own,
+// reporting whether there's one to play. At the end of the playlist it
+// starts over (reshuffled) when looping. Callers hold p.mu.
+func (p *Player) nextTrackLocked(played time.Duration) bool {
+	if len(p.playlist) == 0 {
+		return false
+	}
+	if played < minTrackPlay {
+		p.quickExits++
+	} else {
+		p.quickExits = 0
+	}
+	if p.quickExits >= len(p.playlist) {
+		p.track, p.quickExits = 0, 0
+		return false
+	}
+	p.track++
+	if p.track < len(p.playlist) {
+		return true
+	}
+	p.track = 0
+	if !p.loop {
+		return false
+	}
+	if p.shuffle {
+		rand.Shuffle(len(p.playlist), func(i, j int) { p.playlist[i], p.playlist[j] = p.playlist[j], p.playlist[i] })
+	}
+	return true
+}
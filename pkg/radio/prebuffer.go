@@ -0,0 +1,135 @@
+package radio
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// prerollRate is the byte rate of the WAV audio the pre-roll decoder
+// hands ffplay, for sizing the pre-roll buffer
+const prerollRate = PCMSampleRate * PCMChannels * 2
+
+// prerollingLocked reports whether ffplay plays resolved through the
+// pre-roll buffer. Only remote streams need one; a looped source has to
+// be seekable, and a detached player would outlive the buffer. Callers
+// hold p.mu.
+func (p *Player) prerollingLocked(resolved string) bool {
+	return p.prebuffer > 0 && isHTTPURL(resolved) && !p.loopFile && !p.detach
+}
+
+// prerollLocked returns the pre-roll the current stream starts with, for
+// the stats. Callers hold p.mu.
+func (p *Player) prerollLocked(resolved string) time.Duration {
+	if p.backend == BackendMPV || (p.backend == BackendFFplay && p.prerollingLocked(resolved)) {
+		return p.prebuffer
+	}
+	return 0
+}
+
+// startPreroll starts ffmpeg with args, decoding the stream into a
+// pre-roll buffer. It returns the decoder and the pipe the player reads
+// the buffered audio from, which the caller closes once the player has
+// started. Killing the decoder ends the audio; the player going away
+// stops the decoder.
+func (p *Player) startPreroll(args []string) (*exec.Cmd, *os.File, error) {
+	decoded, decoderOut, err := os.Pipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	playerIn, feed, err := os.Pipe()
+	if err != nil {
+		decoded.Close()
+		decoderOut.Close()
+		return nil, nil, err
+	}
+	cmd := exec.Command(BackendPCM, args...)
+	cmd.Env = p.playerEnv()
+	cmd.Stdout = decoderOut
+	cmd.Stderr = os.Stderr
+	err = cmd.Start()
+	// The decoder has its own copy
+	decoderOut.Close()
+	if err != nil {
+		decoded.Close()
+		playerIn.Close()
+		feed.Close()
+		return nil, nil, err
+	}
+	go func() {
+		err := cmd.Wait()
+		p.log().Debug("pre-roll decoder exited", "pid", cmd.Process.Pid, "err", err)
+	}()
+
+	buf := newPrerollBuffer(int(p.prebuffer.Seconds() * prerollRate))
+	go func() {
+		_, _ = io.Copy(buf, decoded)
+		decoded.Close()
+		buf.Close()
+	}()
+	go func() {
+		// Fails once the player exits; closing buf then makes the copy
+		// above stop, and the decoder with it
+		_, _ = io.Copy(feed, buf)
+		feed.Close()
+		buf.Close()
+	}()
+	return cmd, playerIn, nil
+}
+
+// prerollBuffer holds decoded audio back from the player until it has
+// threshold bytes, then passes everything through as it arrives
+type prerollBuffer struct {
+	mu        sync.Mutex
+	ready     sync.Cond
+	buf       bytes.Buffer
+	threshold int
+	started   bool
+	closed    bool
+}
+
+func newPrerollBuffer(threshold int) *prerollBuffer {
+	b := &prerollBuffer{threshold: threshold}
+	b.ready.L = &b.mu
+	return b
+}
+
+func (b *prerollBuffer) Write(data []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return 0, io.ErrClosedPipe
+	}
+	n, _ := b.buf.Write(data)
+	if b.buf.Len() >= b.threshold {
+		b.started = true
+	}
+	b.ready.Broadcast()
+	return n, nil
+}
+
+// Read waits for the pre-roll, then returns what's buffered. A stream
+// that ends before the pre-roll is in still plays what there is. It
+// returns io.EOF once the buffer is closed and drained.
+func (b *prerollBuffer) Read(data []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for !b.closed && (!b.started || b.buf.Len() == 0) {
+		b.ready.Wait()
+	}
+	if b.buf.Len() == 0 {
+		return 0, io.EOF
+	}
+	return b.buf.Read(data)
+}
+
+// Close ends the audio; Read drains what's left
+func (b *prerollBuffer) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	b.ready.Broadcast()
+}
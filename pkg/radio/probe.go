@@ -0,0 +1,121 @@
+package radio
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultProbeInterval is how often the stream's server gets a HEAD probe.
+// Some Icecast servers count every request as a listener connection, so
+// this stays well above the stats interval.
+const DefaultProbeInterval = 5 * time.Second
+
+// MinProbeInterval keeps users from hammering streams with probes
+const MinProbeInterval = time.Second
+
+// maxProbeBackoff caps the doubling wait after throttled probes; a longer
+// Retry-After from the server is still honored
+const maxProbeBackoff = 10 * time.Minute
+
+// SetProbeInterval sets how often the stream's server is sent a HEAD
+// probe. It takes effect on the next StartAnalysis.
+func (sa *StreamAnalyzer) SetProbeInterval(d time.Duration) error {
+	if d < MinProbeInterval {
+		return fmt.Errorf("probe interval must be at least %v", MinProbeInterval)
+	}
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	sa.probeInterval = d
+	return nil
+}
+
+// probe sends one HEAD request to the stream and records how it went for
+// the network metrics. When the server answers 429 or 503 it reports
+// throttled, with the wait it asked for in Retry-After (0 when none);
+// those answers count toward neither successes nor failures.
+func (sa *StreamAnalyzer) probe(ctx context.Context, url string) (retryAfter time.Duration, throttled bool) {
+	startTime := time.Now()
+	var resp *http.Response
+	var timing *connTiming
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err == nil {
+		sa.mu.RLock()
+		addHeaders(req, sa.headers)
+		// Time the connection until a probe gets an answer
+		if sa.stats.FirstByte == 0 {
+			timing = newConnTiming(startTime)
+			req = req.WithContext(httptrace.WithClientTrace(ctx, timing.trace()))
+		}
+		sa.mu.RUnlock()
+		resp, err = sa.client.Do(req)
+	}
+	requestDuration := time.Since(startTime)
+
+	// A request cut off by StopAnalysis isn't a network failure
+	if ctx.Err() != nil {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return 0, false
+	}
+
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	sa.recordRedirectsLocked(resp, err)
+	if err != nil {
+		sa.failedRequests++
+		return 0, false
+	}
+	resp.Body.Close()
+	if timing != nil {
+		timing.recordLocked(&sa.stats)
+	}
+	// Any answer means the server is up, even one asking us to slow down
+	sa.markConnectedLocked()
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		return parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()), true
+	}
+	sa.successfulRequests++
+
+	// Track request times for jitter calculation
+	sa.requestTimes = append(sa.requestTimes, requestDuration)
+	if len(sa.requestTimes) > 10 {
+		sa.requestTimes = sa.requestTimes[1:] // Keep only last 10
+	}
+	sa.lastRequestTime = startTime
+	return 0, false
+}
+
+// probeBackoff returns how long to wait before probing a server that
+// throttled the last probe: what it asked for, else double the previous
+// wait, starting from twice the probe interval
+func probeBackoff(prev, every, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return retryAfter
+	}
+	if prev == 0 {
+		return min(2*every, maxProbeBackoff)
+	}
+	return min(2*prev, maxProbeBackoff)
+}
+
+// parseRetryAfter reads a Retry-After header, given either as seconds or
+// as an HTTP date. It returns 0 when the header is missing or invalid.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(secs)*time.Second, 0)
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0)
+	}
+	return 0
+}
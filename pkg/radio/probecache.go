@@ -0,0 +1,123 @@
+package radio
+
+import (
+	"net/url"
+	"time"
+)
+
+// DefaultProbeCacheTTL is how long ffprobe's metadata for a station is
+// reused before the station is probed again. A station's codec and
+// bitrate rarely change, so repeat visits can skip the probe.
+const DefaultProbeCacheTTL = 6 * time.Hour
+
+// probeMetadata is what extractMetadata learns from ffprobe
+type probeMetadata struct {
+	Codec         string
+	VideoCodec    string
+	Bitrate       int64
+	SampleRate    int
+	Channels      int
+	ChannelLayout string
+}
+
+// apply copies the metadata into s
+func (m probeMetadata) apply(s *StreamStats) {
+	s.Codec = m.Codec
+	s.VideoCodec = m.VideoCodec
+	s.Bitrate = m.Bitrate
+	s.SampleRate = m.SampleRate
+	s.Channels = m.Channels
+	s.ChannelLayout = m.ChannelLayout
+}
+
+// probeCacheEntry is a station's cached metadata
+type probeCacheEntry struct {
+	meta     probeMetadata
+	resolved string    // the media URL that was probed
+	at       time.Time // when it was probed
+}
+
+// SetProbeCache has the analyzer reuse ffprobe's metadata for a station
+// for ttl, so starting it again shows the codec and bitrate right away
+// without another probe. Entries are kept by station URL; a yt-dlp
+// station whose media URL has changed to a different format is probed
+// again. A ttl of 0 turns the cache off and forgets what's in it.
+func (sa *StreamAnalyzer) SetProbeCache(ttl time.Duration) {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	sa.probeCacheTTL = ttl
+	if ttl <= 0 {
+		sa.probeCache = nil
+	}
+}
+
+// ClearProbeCache forgets every station's cached metadata, e.g. when the
+// station list is reloaded
+func (sa *StreamAnalyzer) ClearProbeCache() {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	sa.probeCache = nil
+}
+
+// SetSource records the station URL that the media URL given to the next
+// StartAnalysis was resolved from, which the probe cache keys on. Without
+// it, the media URL is the key.
+func (sa *StreamAnalyzer) SetSource(station string) {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	sa.source = station
+}
+
+// cachedMetadataLocked returns the cached metadata for station when it's
+// fresh and was probed from the same media as resolved. Callers hold
+// sa.mu.
+func (sa *StreamAnalyzer) cachedMetadataLocked(station, resolved string, now time.Time) (probeMetadata, bool) {
+	if sa.probeCacheTTL <= 0 {
+		return probeMetadata{}, false
+	}
+	entry, ok := sa.probeCache[station]
+	if !ok || now.Sub(entry.at) >= sa.probeCacheTTL || !sameMedia(entry.resolved, resolved) {
+		return probeMetadata{}, false
+	}
+	return entry.meta, true
+}
+
+// cacheMetadataLocked remembers station's metadata, probed from resolved.
+// Callers hold sa.mu.
+func (sa *StreamAnalyzer) cacheMetadataLocked(station, resolved string, meta probeMetadata, now time.Time) {
+	if sa.probeCacheTTL <= 0 {
+		return
+	}
+	if sa.probeCache == nil {
+		sa.probeCache = make(map[string]probeCacheEntry)
+	}
+	sa.probeCache[station] = probeCacheEntry{meta: meta, resolved: resolved, at: now}
+}
+
+// sameMedia reports whether two media URLs resolved for one station are
+// the same stream. yt-dlp's URLs carry signatures and expiry times that
+// change every time, and CDN hosts rotate, so only the path and the format
+// they name (YouTube's itag and mime) are compared.
+func sameMedia(a, b string) bool {
+	if a == b {
+		return true
+	}
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	if ua.Scheme != ub.Scheme || ua.Path != ub.Path {
+		return false
+	}
+	qa, qb := ua.Query(), ub.Query()
+	for _, key := range []string{"itag", "mime"} {
+		if qa.Get(key) != qb.Get(key) {
+			return false
+		}
+	}
+	return true
+}
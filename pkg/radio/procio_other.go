@@ -0,0 +1,16 @@
+//go:build !linux
+
+package radio
+
+import "errors"
+
+// processReadBytes isn't available outside Linux; data usage shows N/A
+func processReadBytes(pid int) (int64, error) {
+	return 0, errors.New("per-process byte counts are only available on Linux")
+}
+
+// processName isn't available outside Linux, so detached players are
+// stopped without checking the PID
+func processName(pid int) (string, error) {
+	return "", errors.New("process names are only available on Linux")
+}
@@ -0,0 +1,55 @@
+package radio
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+)
+
+// proxyEnvVars are checked in order when no --proxy flag is given
+var proxyEnvVars = []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy", "ALL_PROXY", "all_proxy"}
+
+// ParseProxy returns the proxy to use for stream connections. The flag
+// value wins; otherwise the usual proxy environment variables are honored.
+// A nil URL means connect directly.
+func ParseProxy(flagValue string) (*url.URL, error) {
+	raw := flagValue
+	if raw == "" {
+		for _, name := range proxyEnvVars {
+			if v := os.Getenv(name); v != "" {
+				raw = v
+				break
+			}
+		}
+	}
+	if raw == "" {
+		return nil, nil
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q", raw)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+		return u, nil
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q (use http, https, socks5, or socks5h)", u.Scheme)
+	}
+}
+
+// IsHTTPProxy reports whether ffmpeg tools can use the proxy. They only
+// understand HTTP proxies, not SOCKS.
+func IsHTTPProxy(u *url.URL) bool {
+	return u != nil && (u.Scheme == "http" || u.Scheme == "https")
+}
+
+// proxyEnv returns the environment for an ffmpeg tool (ffplay, ffprobe)
+// so it connects through the proxy. It returns nil, meaning inherit the
+// current environment, when there is no usable proxy.
+func proxyEnv(u *url.URL) []string {
+	if !IsHTTPProxy(u) {
+		return nil
+	}
+	return append(os.Environ(), "http_proxy="+u.String())
+}
@@ -0,0 +1,81 @@
+package radio
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// maxRedirects is how many redirects the analyzer follows before giving
+// up on a stream; http.Client's own default
+const maxRedirects = 10
+
+// longRedirectChain is the most redirects a stream takes before the
+// quality alerts point it out. Directory links commonly take two or three.
+const longRedirectChain = 5
+
+// Redirect chains the analyzer refuses to follow
+var (
+	errRedirectLoop     = errors.New("redirect loop")
+	errTooManyRedirects = fmt.Errorf("more than %d redirects", maxRedirects)
+)
+
+// checkRedirect is the analyzer client's CheckRedirect. It stops at a URL
+// the chain has already been through, rather than going round until the
+// redirect limit.
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	for _, prev := range via {
+		if prev.URL.String() == req.URL.String() {
+			return fmt.Errorf("%w back to %s", errRedirectLoop, RedactURL(req.URL.String()))
+		}
+	}
+	if len(via) >= maxRedirects {
+		return errTooManyRedirects
+	}
+	return nil
+}
+
+// redirectStats reads a response's redirect chain: where it ended, how
+// many redirects it took, and whether one of them went from https to http
+func redirectStats(resp *http.Response) (final *url.URL, hops int, downgraded bool) {
+	final = resp.Request.URL
+	for req := resp.Request; req.Response != nil; req = req.Response.Request {
+		hops++
+		if req.Response.Request.URL.Scheme == "https" && req.URL.Scheme == "http" {
+			downgraded = true
+		}
+	}
+	return final, hops, downgraded
+}
+
+// recordRedirectsLocked notes how a probe's redirects went in the stats:
+// the chain of a response, or why the chain was refused for an error.
+// Callers hold sa.mu.
+func (sa *StreamAnalyzer) recordRedirectsLocked(resp *http.Response, err error) {
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) && (errors.Is(urlErr.Err, errRedirectLoop) || errors.Is(urlErr.Err, errTooManyRedirects)) {
+			sa.stats.RedirectError = urlErr.Err.Error()
+		}
+		return
+	}
+	final, hops, downgraded := redirectStats(resp)
+	sa.stats.FinalURL = ""
+	if hops > 0 {
+		sa.stats.FinalURL = RedactURL(final.String())
+	}
+	sa.stats.Redirects = hops
+	sa.stats.Downgraded = downgraded
+	sa.stats.RedirectError = ""
+}
+
+// FinalHost returns the host the stream's redirects end at, or "" when it
+// isn't redirected
+func (s StreamStats) FinalHost() string {
+	u, err := url.Parse(s.FinalURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
@@ -0,0 +1,126 @@
+package radio
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// DefaultReprobeInterval is how often a playing stream is run through
+// ffprobe again to catch a change of codec, bitrate, or sample rate.
+// Each run opens one more short connection, so it stays long.
+const DefaultReprobeInterval = 10 * time.Minute
+
+// MinReprobeInterval keeps the re-probes from adding real load
+const MinReprobeInterval = time.Minute
+
+// formatChangeAlertFor is how long a format change stays in the quality
+// alerts
+const formatChangeAlertFor = 5 * time.Minute
+
+// bitrateTolerance is how far apart, as a share, two bitrates of a stream
+// can be and still count as the same; VBR streams drift a little
+const bitrateTolerance = 0.1
+
+// SetReprobeInterval sets how often a playing http(s) stream is probed
+// again with ffprobe, to catch adaptive streams that switch codec,
+// bitrate, or sample rate mid-playback. A change updates the stats, is
+// counted in them, stays in the quality alerts for a few minutes, and
+// goes to the OnFormatChange handler. yt-dlp streams are skipped, since
+// their media URL is fixed once resolved. 0 turns the re-probes off. It
+// takes effect on the next StartAnalysis.
+func (sa *StreamAnalyzer) SetReprobeInterval(d time.Duration) error {
+	if d != 0 && d < MinReprobeInterval {
+		return fmt.Errorf("re-probe interval must be at least %v, or 0 to turn it off", MinReprobeInterval)
+	}
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	sa.reprobeInterval = d
+	return nil
+}
+
+// OnFormatChange registers fn to be called with a description of each
+// format change the re-probes find, e.g. "mp3 128 kbps 44100 Hz -> aac
+// 64 kbps 48000 Hz". fn is called without the analyzer's lock held.
+func (sa *StreamAnalyzer) OnFormatChange(fn func(change string)) {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	sa.onFormatChange = fn
+}
+
+// monitorFormat runs ffprobe on url every interval and records any change
+// in the stream's format. A failed run is skipped; the stream may be
+// between reconnects.
+func (sa *StreamAnalyzer) monitorFormat(ctx context.Context, station, url string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		meta, err := sa.readMetadata(ctx, url)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			continue
+		}
+		var change string
+		sa.updateStats(func(s *StreamStats) {
+			before := probeMetadata{Codec: s.Codec, Bitrate: s.Bitrate, SampleRate: s.SampleRate}
+			// The first probe may have failed; a late answer isn't a change
+			if before.Codec != "" && before.Codec != "Unknown" && formatChanged(before, meta) {
+				change = describeFormat(before) + " -> " + describeFormat(meta)
+				s.FormatChanges++
+				s.FormatChange = change
+				sa.formatChangedAt = time.Now()
+			}
+			meta.apply(s)
+			s.MetadataError = ""
+			sa.cacheMetadataLocked(station, url, meta, time.Now())
+		})
+		if change == "" {
+			continue
+		}
+		sa.mu.RLock()
+		fn := sa.onFormatChange
+		sa.mu.RUnlock()
+		if fn != nil {
+			fn(change)
+		}
+	}
+}
+
+// formatChanged reports whether the codec, sample rate, or bitrate differ
+// between two probes of a stream. Values a probe didn't get aren't
+// compared, and bitrates within bitrateTolerance count as the same.
+func formatChanged(a, b probeMetadata) bool {
+	if a.Codec != b.Codec {
+		return true
+	}
+	if a.SampleRate > 0 && b.SampleRate > 0 && a.SampleRate != b.SampleRate {
+		return true
+	}
+	if a.Bitrate > 0 && b.Bitrate > 0 {
+		diff := math.Abs(float64(a.Bitrate - b.Bitrate))
+		return diff > bitrateTolerance*float64(max(a.Bitrate, b.Bitrate))
+	}
+	return false
+}
+
+// describeFormat names a stream's format, e.g. "mp3 128 kbps 44100 Hz",
+// leaving out what isn't known
+func describeFormat(m probeMetadata) string {
+	s := m.Codec
+	if m.Bitrate > 0 {
+		s += fmt.Sprintf(" %d kbps", m.Bitrate/1000)
+	}
+	if m.SampleRate > 0 {
+		s += fmt.Sprintf(" %d Hz", m.SampleRate)
+	}
+	return s
+}
@@ -0,0 +1,199 @@
+package radio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ResolveOptions configures how yt-dlp resolves stream URLs
+type ResolveOptions struct {
+	Proxy              string      // passed to yt-dlp --proxy when set
+	Cookies            string      // Netscape cookies file for yt-dlp --cookies
+	CookiesFromBrowser string      // browser name for yt-dlp --cookies-from-browser
+	Headers            http.Header // sent with yt-dlp --add-header
+	Format             string      // yt-dlp -f selector; defaults to bestaudio/best
+	Trace              io.Writer   // when set, yt-dlp command lines are printed to it
+	IPVersion          int         // IPv4 or IPv6 passes yt-dlp --force-ipv4 or --force-ipv6
+}
+
+// ytdlpArgs returns the yt-dlp options shared by every invocation
+func (o ResolveOptions) ytdlpArgs() []string {
+	var args []string
+	if o.Proxy != "" {
+		args = append(args, "--proxy", o.Proxy)
+	}
+	switch o.IPVersion {
+	case IPv4:
+		args = append(args, "--force-ipv4")
+	case IPv6:
+		args = append(args, "--force-ipv6")
+	}
+	if o.Cookies != "" {
+		args = append(args, "--cookies", o.Cookies)
+	}
+	if o.CookiesFromBrowser != "" {
+		args = append(args, "--cookies-from-browser", o.CookiesFromBrowser)
+	}
+	for k, values := range o.Headers {
+		for _, v := range values {
+			args = append(args, "--add-header", k+":"+v)
+		}
+	}
+	return args
+}
+
+// Resolution retries: transient yt-dlp failures (rate limits, network
+// errors, timeouts) are retried with exponential backoff and jitter
+const (
+	resolveAttempts = 3
+	resolveBackoff  = time.Second      // before the first retry; doubles after
+	resolveTimeout  = 30 * time.Second // per attempt; yt-dlp is killed after
+)
+
+// ResolvePlayableURL returns a direct media URL that ffplay can consume.
+// Page URLs (YouTube, SoundCloud, Bandcamp, and the other sites yt-dlp
+// supports) go through yt-dlp -g to get the direct audio URL (same as your working command).
+func ResolvePlayableURL(originalURL string, opts ResolveOptions) (string, error) {
+	if !NeedsResolution(originalURL) {
+		return originalURL, nil
+	}
+
+	if opts.Trace != nil {
+		fmt.Fprintln(opts.Trace, commandLine(nil, ytdlpCommand(originalURL, opts)))
+	}
+	var err error
+	for attempt := range resolveAttempts {
+		if attempt > 0 {
+			time.Sleep(resolveDelay(attempt))
+		}
+		var resolved string
+		if resolved, err = resolveOnce(originalURL, opts); err == nil {
+			return resolved, nil
+		}
+		var resolveErr *ResolveError
+		if !errors.As(err, &resolveErr) || !resolveErr.Kind.transient() {
+			break
+		}
+	}
+	return "", err
+}
+
+// resolveDelay returns how long to wait before a retry: resolveBackoff
+// doubled for each earlier retry, randomized by up to half either way so
+// clients don't retry in step
+func resolveDelay(attempt int) time.Duration {
+	d := resolveBackoff << (attempt - 1)
+	return d/2 + rand.N(d)
+}
+
+// ytdlpCommand returns the yt-dlp command line that resolves a URL
+func ytdlpCommand(originalURL string, opts ResolveOptions) []string {
+	// Use yt-dlp -g to get the direct audio URL (same as your working command)
+	format := opts.Format
+	if format == "" {
+		format = defaultYtdlpFormat
+	}
+	args := []string{ytdlpBinary, "-g", "-f", format}
+	args = append(args, opts.ytdlpArgs()...)
+	return append(args, originalURL)
+}
+
+// resolveOnce runs yt-dlp -g once, killing it after resolveTimeout
+func resolveOnce(originalURL string, opts ResolveOptions) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+	args := ytdlpCommand(originalURL, opts)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.WaitDelay = time.Second // Don't hang on grandchildren holding the pipe
+	var stdout, stderr strings.Builder
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		kind := classifyYtdlpError(stderr.String())
+		if ctx.Err() == context.DeadlineExceeded {
+			kind = ytdlpTimedOut
+		}
+		return "", &ResolveError{Kind: kind, Stderr: stderr.String(), Err: err}
+	}
+
+	output := strings.TrimSpace(stdout.String())
+	if output == "" {
+		return "", fmt.Errorf("yt-dlp did not return a media URL, stderr: %s", stderr.String())
+	}
+
+	// Return the first line (the audio URL)
+	lines := strings.Split(output, "\n")
+	return strings.TrimSpace(lines[0]), nil
+}
+
+func isHTTPURL(u string) bool {
+	lower := strings.ToLower(u)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// directMediaRegexp matches URLs that are already playable: audio files,
+// playlists, and typical Icecast/SHOUTcast mount points. A stream, listen,
+// or live mount is a whole path segment: page slugs such as /live-from-x
+// or /listen-closely only start with the word.
+var directMediaRegexp = regexp.MustCompile(`(?i)(\.(mp3|ogg|oga|opus|aac|m4a|flac|wav|m3u8?|pls)$|icecast|shoutcast|/(stream|listen|live)(/|$)|;$|:\d{4,5}(/|$))`)
+
+// NeedsResolution reports whether a URL should go through yt-dlp. YouTube
+// always does; other http(s) URLs do unless they look like direct media.
+// Non-HTTP URLs (rtmp, file paths, ...) are passed to the player as is.
+func NeedsResolution(u string) bool {
+	if isYouTubeURL(u) {
+		return true
+	}
+	if !isHTTPURL(u) {
+		return false
+	}
+	path := u
+	if i := strings.IndexAny(path, "?#"); i >= 0 {
+		path = path[:i]
+	}
+	return !directMediaRegexp.MatchString(path)
+}
+
+// ytRegexp matches YouTube hosts, with or without a scheme: youtu.be short
+// links and the www, mobile, music, and no-cookie sites
+var ytRegexp = regexp.MustCompile(`(?i)^(https?://)?((www|m|music)\.)?(youtube\.com|youtube-nocookie\.com|youtu\.be)/`)
+
+func isYouTubeURL(u string) bool {
+	return ytRegexp.MatchString(u)
+}
+
+// CheckDependencies verifies that required external tools are available
+func CheckDependencies(backend string) error {
+	// Check for the playback backend (ffplay or mpv)
+	if _, err := exec.LookPath(backend); err != nil {
+		return fmt.Errorf("%s not found. %s", backend, installHints[backend])
+	}
+
+	// Check for yt-dlp (needed for YouTube URLs) - use system yt-dlp
+	if _, err := exec.LookPath("yt-dlp"); err != nil {
+		return fmt.Errorf("yt-dlp not found. Please install yt-dlp: sudo curl -L https://github.com/yt-dlp/yt-dlp/releases/latest/download/yt-dlp -o /usr/local/bin/yt-dlp && sudo chmod a+rx /usr/local/bin/yt-dlp")
+	}
+
+	return nil
+}
+
+// CheckFFprobe reports whether ffprobe is available. It's optional: the
+// stats use it for the codec, bitrate, and sample rate, and playback works
+// without it.
+func CheckFFprobe() error {
+	if _, err := exec.LookPath("ffprobe"); err != nil {
+		return fmt.Errorf("ffprobe not found, so the stats can't show the codec or bitrate. %s", installHints[BackendFFplay])
+	}
+	return nil
+}
+
+// ytdlpBinary is the yt-dlp executable used to resolve page URLs
+var ytdlpBinary = "yt-dlp"
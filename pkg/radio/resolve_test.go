@@ -0,0 +1,40 @@
+package radio
+
+import "testing"
+
+func TestNeedsResolution(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		// Pages yt-dlp has to find the audio in
+		{"https://www.youtube.com/watch?v=jfKfPfyJRdk", true},
+		{"https://youtu.be/jfKfPfyJRdk", true},
+		{"https://www.youtube.com/live/jfKfPfyJRdk", true},
+		{"https://soundcloud.com/boilerroom/live-from-dekmantel", true},
+		{"https://artist.bandcamp.com/track/listen-closely", true},
+		{"https://www.mixcloud.com/dj/stream-of-consciousness/", true},
+		{"https://soundcloud.com/artist/livestream", true},
+
+		// Direct streams
+		{"https://stream.example.com/stream", false},
+		{"https://radio.example.com/listen/", false},
+		{"https://radio.example.com/live?token=abc", false},
+		{"https://radio.example.com/station/live/", false},
+		{"https://icecast.example.org/jazz", false},
+		{"http://radio.example.com:8000/", false},
+		{"http://radio.example.com:8000/jazz", false},
+		{"http://radio.example.com/;", false},
+		{"https://cdn.example.com/hls/playlist.m3u8", false},
+		{"https://cdn.example.com/audio/show.MP3?dl=1", false},
+
+		// Not HTTP: played as is
+		{"rtmp://radio.example.com/live", false},
+		{"/home/me/music/track.flac", false},
+	}
+	for _, tt := range tests {
+		if got := NeedsResolution(tt.url); got != tt.want {
+			t.Errorf("NeedsResolution(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
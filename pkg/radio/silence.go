@@ -0,0 +1,105 @@
+package radio
+
+import (
+	"bufio"
+	"context"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// silenceNoise is the level below which the silence watch counts audio as
+// silence: ffmpeg's own default, well under a quiet passage
+const silenceNoise = "-60dB"
+
+// SetSilenceDetect has the analyzer watch http(s) streams for silence, as
+// from a dead mount that still answers: after is how long the audio must
+// stay silent before the stream is reported, in the stats and quality
+// alerts and to onSilent (which may be nil). 0 turns the watch off.
+// ffmpeg decodes a second copy of the stream for it, so it roughly
+// doubles bandwidth. It takes effect on the next StartAnalysis.
+func (sa *StreamAnalyzer) SetSilenceDetect(after time.Duration, onSilent func()) {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	sa.silenceAfter = after
+	sa.onSilent = onSilent
+}
+
+// monitorSilence runs ffmpeg's silencedetect filter over url in real time
+// and marks the stream silent from each silence_start it reports until
+// the matching silence_end. It returns quietly when ffmpeg is missing or
+// the stream ends.
+func (sa *StreamAnalyzer) monitorSilence(ctx context.Context, url string, after time.Duration) {
+	sa.mu.RLock()
+	args := []string{"-nostdin", "-hide_banner", "-nostats", "-loglevel", "info", "-re"}
+	if len(sa.headers) > 0 {
+		args = append(args, "-headers", ffmpegHeaders(sa.headers))
+	}
+	args = append(args, "-i", url, "-vn",
+		"-af", "silencedetect=noise="+silenceNoise+":d="+strconv.FormatFloat(after.Seconds(), 'f', -1, 64),
+		"-f", "null", "-")
+	cmd := exec.CommandContext(ctx, BackendPCM, args...)
+	cmd.Env = proxyEnv(sa.proxy)
+	sa.mu.RUnlock()
+	cmd.WaitDelay = time.Second
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return
+	}
+	if err := cmd.Start(); err != nil {
+		return
+	}
+	defer func() { _ = cmd.Wait() }()
+
+	// silencedetect logs "[silencedetect @ 0x...] silence_start: 31.2"
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.Contains(line, "silence_start:"):
+			sa.mu.Lock()
+			if ctx.Err() != nil {
+				sa.mu.Unlock()
+				return
+			}
+			sa.stats.Silent = true
+			onSilent := sa.onSilent
+			sa.mu.Unlock()
+			if onSilent != nil {
+				onSilent()
+			}
+		case strings.Contains(line, "silence_end:"):
+			sa.mu.Lock()
+			if ctx.Err() == nil {
+				sa.stats.Silent = false
+			}
+			sa.mu.Unlock()
+		}
+	}
+}
+
+// WithSilenceDetect reports a stream that has been silent for after in
+// the analyzer's stats and quality alerts (see
+// StreamAnalyzer.SetSilenceDetect). With skip, the silent stream is then
+// treated as a dead one: it moves on to the station's next mirror, or
+// stops and goes to the OnUnreachable handler.
+func WithSilenceDetect(after time.Duration, skip bool) Option {
+	return func(p *Player) {
+		var onSilent func()
+		if skip {
+			onSilent = p.silent
+		}
+		p.analyzer.SetSilenceDetect(after, onSilent)
+	}
+}
+
+// silent gives up on the playing stream once the analyzer finds it silent
+func (p *Player) silent() {
+	p.mu.Lock()
+	cmd, station := p.cmd, p.playingURL
+	p.mu.Unlock()
+	if cmd != nil {
+		p.streamFailed(cmd, station, "stream is silent")
+	}
+}
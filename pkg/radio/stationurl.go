@@ -0,0 +1,110 @@
+package radio
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// streamSchemes are the URL schemes ffplay/mpv can open besides http(s)
+var streamSchemes = map[string]bool{
+	"http": true, "https": true, "file": true,
+	"rtmp": true, "rtmps": true, "rtsp": true, "rtp": true, "srt": true,
+	"udp": true, "tcp": true, "mms": true, "mmsh": true,
+}
+
+// schemeTypos are misspellings worth pointing out by name
+var schemeTypos = map[string]string{
+	"htp": "http", "htttp": "http", "hhtp": "http",
+	"htps": "https", "httsp": "https", "htttps": "https",
+}
+
+// NormalizeStationURL trims and checks a station URL, returning the form
+// to play. Problems that make the URL unusable are errors; likely typos
+// that might still work are returned as warnings.
+func NormalizeStationURL(raw string) (string, []string, error) {
+	var warnings []string
+	s := strings.TrimSpace(raw)
+	if s == "" {
+		return "", nil, errors.New("missing url")
+	}
+	// Local files and directories, which may well have spaces in them
+	if rest, ok := strings.CutPrefix(s, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			s = filepath.Join(home, rest)
+		}
+	}
+	if _, err := os.Stat(s); err == nil {
+		return s, nil, nil
+	}
+	if strings.ContainsAny(s, " \t\n") {
+		return "", nil, errors.New("url contains whitespace (encode spaces as %20)")
+	}
+	if ytRegexp.MatchString(s) && !isHTTPURL(s) {
+		s = "https://" + s
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid url: %w", err)
+	}
+	scheme := strings.ToLower(u.Scheme)
+	switch {
+	case scheme == "" || len(scheme) == 1:
+		// Not an existing local path (a one-letter scheme is a Windows
+		// drive letter)
+		return "", nil, fmt.Errorf("%q is neither a URL with a scheme nor an existing file", s)
+	case schemeTypos[scheme] != "":
+		return "", nil, fmt.Errorf("unknown scheme %q (did you mean %s://?)", u.Scheme, schemeTypos[scheme])
+	case !streamSchemes[scheme]:
+		return "", nil, fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+
+	if scheme == "http" || scheme == "https" {
+		if u.Host == "" {
+			return "", nil, errors.New("url has no host (check the slashes after the scheme)")
+		}
+		host := u.Hostname()
+		if !strings.Contains(host, ".") && !strings.Contains(host, ":") && host != "localhost" {
+			warnings = append(warnings, fmt.Sprintf("host %q has no domain suffix", host))
+		}
+		if strings.Contains(host, "..") {
+			warnings = append(warnings, fmt.Sprintf("host %q contains an empty label", host))
+		}
+	}
+
+	if id, ok := youTubeVideoID(u); ok {
+		return "https://www.youtube.com/watch?v=" + id, warnings, nil
+	}
+	return u.String(), warnings, nil
+}
+
+// youTubeVideoID extracts the video ID from the various YouTube URL forms:
+// watch?v=ID, youtu.be/ID, /live/ID, /shorts/ID, and /embed/ID. Channel
+// and playlist URLs have no single video ID and are left alone.
+func youTubeVideoID(u *url.URL) (string, bool) {
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	path := strings.Trim(u.Path, "/")
+	var id string
+	switch host {
+	case "youtu.be":
+		id = path
+	case "youtube.com", "m.youtube.com", "music.youtube.com", "youtube-nocookie.com":
+		if path == "watch" {
+			id = u.Query().Get("v")
+			break
+		}
+		for _, prefix := range []string{"live/", "shorts/", "embed/"} {
+			if rest, ok := strings.CutPrefix(path, prefix); ok {
+				id = rest
+			}
+		}
+	}
+	if id == "" || strings.Contains(id, "/") {
+		return "", false
+	}
+	return id, true
+}
@@ -0,0 +1,363 @@
+package radio
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCalculateJitter(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name  string
+		times []time.Duration
+		want  time.Duration
+	}{
+		{"no samples", nil, 0},
+		{"one sample", []time.Duration{300 * ms}, 0},
+		{"constant", []time.Duration{120 * ms, 120 * ms, 120 * ms}, 0},
+		// Mean 500ms, deviations of 300ms each way
+		{"two apart", []time.Duration{200 * ms, 800 * ms}, 300 * ms},
+		// The textbook set: mean 5, population variance 4
+		{"known set", []time.Duration{2 * ms, 4 * ms, 4 * ms, 4 * ms, 5 * ms, 5 * ms, 7 * ms, 9 * ms}, 2 * ms},
+		// Squaring these as Durations would overflow int64
+		{"large", []time.Duration{10 * time.Second, 20 * time.Second}, 5 * time.Second},
+	}
+	sa := NewStreamAnalyzer()
+	for _, tt := range tests {
+		if got := sa.calculateJitter(tt.times); got != tt.want {
+			t.Errorf("%s: calculateJitter(%v) = %v, want %v", tt.name, tt.times, got, tt.want)
+		}
+	}
+}
+
+func TestClampPercent(t *testing.T) {
+	tests := []struct {
+		in, want float64
+	}{
+		{-5, 0},
+		{0, 0},
+		{42.5, 42.5},
+		{100, 100},
+		{250, 100},
+		{math.NaN(), 0},
+		{math.Inf(1), 0},
+		{math.Inf(-1), 0},
+	}
+	for _, tt := range tests {
+		if got := clampPercent(tt.in); got != tt.want {
+			t.Errorf("clampPercent(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseProbeInt(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"", 0},
+		{"N/A", 0},
+		{"128000", 128000},
+		{" 44100\n", 44100},
+		{"0", 0},
+		{"-1", 0},
+		{"12.5", 0},
+		{"abc", 0},
+	}
+	for _, tt := range tests {
+		if got := parseProbeInt(tt.in); got != tt.want {
+			t.Errorf("parseProbeInt(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestAssessNetworkQualityStartup(t *testing.T) {
+	tests := []struct {
+		name  string
+		stats StreamStats
+		want  string
+	}{
+		{"nothing yet", StreamStats{}, "Unknown"},
+		{"zero bitrate", StreamStats{DownloadSpeed: 16000, Samples: 5, ConnectionStability: 100}, "Unknown"},
+		{"no speed", StreamStats{Bitrate: 128000, Samples: 5, ConnectionStability: 100}, "Unknown"},
+		{"too few probes", StreamStats{Bitrate: 128000, DownloadSpeed: 16000, Samples: minQualitySamples - 1}, "Unknown"},
+		{"infinite speed", StreamStats{Bitrate: 128000, DownloadSpeed: math.Inf(1), DataMeasured: true}, "Unknown"},
+		{"NaN speed", StreamStats{Bitrate: 128000, DownloadSpeed: math.NaN(), DataMeasured: true}, "Unknown"},
+		{"enough data", StreamStats{Bitrate: 128000, DownloadSpeed: 32000, BufferHealth: 90, Samples: 5, ConnectionStability: 100}, "Excellent"},
+	}
+	for _, tt := range tests {
+		sa := NewStreamAnalyzer()
+		sa.stats = tt.stats
+		if got := sa.assessNetworkQuality(); got != tt.want {
+			t.Errorf("%s: assessNetworkQuality() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestConnectionStabilityWithoutRequests(t *testing.T) {
+	sa := NewStreamAnalyzer()
+	if got := sa.calculateConnectionStability(); got != 100 {
+		t.Errorf("calculateConnectionStability() with no requests = %v, want 100", got)
+	}
+	sa.successfulRequests, sa.failedRequests = 3, 1
+	if got := sa.calculateConnectionStability(); got != 75 {
+		t.Errorf("calculateConnectionStability() with 3 of 4 answered = %v, want 75", got)
+	}
+}
+
+// newTestAnalyzer returns an analyzer that doesn't run ffprobe or re-probe
+func newTestAnalyzer(t *testing.T) *StreamAnalyzer {
+	t.Helper()
+	sa := NewStreamAnalyzer()
+	sa.SetFFprobe("drift-radio-test-no-ffprobe")
+	if err := sa.SetReprobeInterval(0); err != nil {
+		t.Fatal(err)
+	}
+	return sa
+}
+
+// waitConnected waits for the analyzer's probes to reach the server
+func waitConnected(t *testing.T, sa *StreamAnalyzer) {
+	t.Helper()
+	select {
+	case <-sa.Connected():
+	case <-time.After(5 * time.Second):
+		t.Fatal("the analyzer never probed the stream")
+	}
+}
+
+func TestAnalysisRunsAgainAfterStop(t *testing.T) {
+	probes := make(chan struct{}, 16)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			probes <- struct{}{}
+		}
+	}))
+	defer srv.Close()
+
+	sa := newTestAnalyzer(t)
+	for i := range 3 {
+		if err := sa.StartAnalysis(context.Background(), srv.URL); err != nil {
+			t.Fatal(err)
+		}
+		waitConnected(t, sa)
+		sa.StopAnalysis()
+		select {
+		case <-probes:
+		default:
+			t.Fatalf("start %d: connected without a probe", i+1)
+		}
+	}
+}
+
+// fakeStream serves a stand-in radio stream: HEAD probes are answered
+// after latency, every failEvery-th request has its connection dropped
+// unanswered, and GETs stream zeros at bytesPerSecond until the client
+// goes away
+type fakeStream struct {
+	latency        time.Duration
+	failEvery      int
+	bytesPerSecond int
+
+	mu       sync.Mutex
+	requests int
+}
+
+func (f *fakeStream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.requests++
+	n := f.requests
+	f.mu.Unlock()
+	time.Sleep(f.latency)
+	if f.failEvery > 0 && n%f.failEvery == 0 {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			conn.Close()
+		}
+		return
+	}
+	// A fresh connection per request, so a dropped one is never retried
+	// by the client's transport
+	w.Header().Set("Connection", "close")
+	w.Header().Set("Content-Type", "audio/mpeg")
+	if r.Method != http.MethodGet || f.bytesPerSecond == 0 {
+		return
+	}
+	const ticks = 10
+	chunk := make([]byte, f.bytesPerSecond/ticks)
+	ticker := time.NewTicker(time.Second / ticks)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+		if _, err := w.Write(chunk); err != nil {
+			return
+		}
+		w.(http.Flusher).Flush()
+	}
+}
+
+// startFakeStream starts serving f and returns its URL
+func startFakeStream(t *testing.T, f *fakeStream) string {
+	t.Helper()
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+// startQuickAnalysis analyses url with the monitors ticking every 20ms
+func startQuickAnalysis(t *testing.T, url string) *StreamAnalyzer {
+	t.Helper()
+	sa := newTestAnalyzer(t)
+	sa.setIntervals(20*time.Millisecond, 20*time.Millisecond)
+	if err := sa.StartAnalysis(context.Background(), url); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(sa.StopAnalysis)
+	return sa
+}
+
+// waitStats polls the analyzer's stats until done accepts them
+func waitStats(t *testing.T, sa *StreamAnalyzer, done func(StreamStats) bool) StreamStats {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		stats := sa.GetStats()
+		if done(stats) {
+			return stats
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("stats never got there: %+v", stats)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestAnalyzerHealthyStream(t *testing.T) {
+	sa := startQuickAnalysis(t, startFakeStream(t, &fakeStream{}))
+	stats := waitStats(t, sa, func(s StreamStats) bool { return s.Samples >= 5 })
+	if stats.PacketLoss != 0 {
+		t.Errorf("packet loss = %v, want 0", stats.PacketLoss)
+	}
+	if stats.ConnectionStability != 100 {
+		t.Errorf("stability = %v, want 100", stats.ConnectionStability)
+	}
+	if stats.FirstByte <= 0 {
+		t.Errorf("first byte time = %v, want it measured", stats.FirstByte)
+	}
+}
+
+func TestAnalyzerIntermittentFailures(t *testing.T) {
+	// Every third probe goes unanswered
+	sa := startQuickAnalysis(t, startFakeStream(t, &fakeStream{failEvery: 3}))
+	stats := waitStats(t, sa, func(s StreamStats) bool { return s.Samples >= 9 })
+	failed := stats.Samples / 3
+	wantLoss := float64(failed) / float64(stats.Samples) * 100
+	if stats.PacketLoss != wantLoss {
+		t.Errorf("packet loss after %d probes = %v, want %v", stats.Samples, stats.PacketLoss, wantLoss)
+	}
+	if want := 100 - wantLoss; math.Abs(stats.ConnectionStability-want) > 1e-9 {
+		t.Errorf("stability after %d probes = %v, want %v", stats.Samples, stats.ConnectionStability, want)
+	}
+}
+
+func TestAnalyzerLatency(t *testing.T) {
+	const latency = 50 * time.Millisecond
+	sa := startQuickAnalysis(t, startFakeStream(t, &fakeStream{latency: latency}))
+	stats := waitStats(t, sa, func(s StreamStats) bool { return s.Samples >= 3 })
+	if stats.FirstByte < latency {
+		t.Errorf("first byte time = %v, want at least the server's %v", stats.FirstByte, latency)
+	}
+	// Every probe waits the same; the spread is scheduling noise
+	if stats.Jitter > latency {
+		t.Errorf("jitter = %v for a steady %v server", stats.Jitter, latency)
+	}
+	if stats.PacketLoss != 0 {
+		t.Errorf("packet loss = %v, want 0", stats.PacketLoss)
+	}
+}
+
+func TestAnalysisLeavesNoGoroutines(t *testing.T) {
+	url := startFakeStream(t, &fakeStream{})
+	sa := newTestAnalyzer(t)
+	sa.setIntervals(20*time.Millisecond, 20*time.Millisecond)
+	before := runtime.NumGoroutine()
+
+	for range 10 {
+		if err := sa.StartAnalysis(context.Background(), url); err != nil {
+			t.Fatal(err)
+		}
+		waitConnected(t, sa)
+		sa.StopAnalysis()
+	}
+	// Cancelling the parent context must stop the monitors just the same
+	for range 10 {
+		ctx, cancel := context.WithCancel(context.Background())
+		if err := sa.StartAnalysis(ctx, url); err != nil {
+			t.Fatal(err)
+		}
+		waitConnected(t, sa)
+		cancel()
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<20)
+			t.Fatalf("%d goroutines before, %d after:\n%s",
+				before, runtime.NumGoroutine(), buf[:runtime.Stack(buf, true)])
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestVideoOnlyStream(t *testing.T) {
+	fixture, err := os.ReadFile(filepath.Join("testdata", "ffprobe_video_only.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out FFProbeOutput
+	if err := json.Unmarshal(fixture, &out); err != nil {
+		t.Fatal(err)
+	}
+	if s := firstAudioStream(out.Streams); s != nil {
+		t.Errorf("firstAudioStream = %+v, want nil", *s)
+	}
+	if got := videoCodec(out.Streams); got != "h264" {
+		t.Errorf("videoCodec = %q, want h264", got)
+	}
+
+	if runtime.GOOS == "windows" {
+		t.Skip("the stand-in ffprobe is a shell script")
+	}
+	// An ffprobe that answers with the fixture
+	ffprobe := filepath.Join(t.TempDir(), "ffprobe")
+	abs, err := filepath.Abs(filepath.Join("testdata", "ffprobe_video_only.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(ffprobe, []byte("#!/bin/sh\nexec cat '"+abs+"'\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	sa := NewStreamAnalyzer()
+	sa.SetFFprobe(ffprobe)
+	meta, err := sa.readMetadata(context.Background(), "https://video.example/live.ts")
+	if err == nil || err.Error() != "no audio track" {
+		t.Errorf("readMetadata error = %v, want no audio track", err)
+	}
+	if meta.VideoCodec != "h264" {
+		t.Errorf("readMetadata video codec = %q, want h264", meta.VideoCodec)
+	}
+}
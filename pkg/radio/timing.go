@@ -0,0 +1,130 @@
+package radio
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+)
+
+// connTiming collects how long each phase of one request took, from its
+// httptrace callbacks. Phases are summed over the redirects, whose
+// connections each go through them again. The callbacks can run on the
+// transport's dialing goroutines, hence the lock.
+type connTiming struct {
+	mu           sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	connectStart map[string]time.Time // by address; dials can race
+	tlsStart     time.Time
+	dns          time.Duration
+	connect      time.Duration
+	tls          time.Duration
+	firstByte    time.Duration
+	dialed       bool // a connection was made rather than reused
+}
+
+// newConnTiming starts timing a request sent at start
+func newConnTiming(start time.Time) *connTiming {
+	return &connTiming{start: start, connectStart: make(map[string]time.Time)}
+}
+
+// trace returns the callbacks that fill t in
+func (t *connTiming) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.dns += time.Since(t.dnsStart)
+		},
+		ConnectStart: func(_, addr string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.connectStart[addr] = time.Now()
+		},
+		ConnectDone: func(_, addr string, err error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			// The dial that lost a race doesn't hold up the request
+			if start, ok := t.connectStart[addr]; ok && err == nil {
+				t.connect += time.Since(start)
+			}
+		},
+		TLSHandshakeStart: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.tls += time.Since(t.tlsStart)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.dialed = t.dialed || !info.Reused
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			// The last one is the final server's, after any redirects
+			t.firstByte = time.Since(t.start)
+		},
+	}
+}
+
+// recordLocked puts the timing in stats. Callers hold sa.mu.
+func (t *connTiming) recordLocked(s *StreamStats) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s.DNSTime = t.dns
+	s.ConnectTime = t.connect
+	s.TLSTime = t.tls
+	s.FirstByte = t.firstByte
+	s.ConnReused = !t.dialed
+}
+
+// formatTiming describes the connection timing for FormatStats, e.g.
+// "DNS 12ms, connect 31ms, TLS 84ms, first byte 210ms". Phases that didn't
+// happen, such as DNS for an IP address, are left out.
+func formatTiming(s StreamStats) string {
+	if s.FirstByte == 0 {
+		return "N/A"
+	}
+	var parts []string
+	for _, phase := range []struct {
+		name string
+		d    time.Duration
+	}{
+		{"DNS", s.DNSTime},
+		{"connect", s.ConnectTime},
+		{"TLS", s.TLSTime},
+		{"first byte", s.FirstByte},
+	} {
+		if phase.d > 0 {
+			parts = append(parts, fmt.Sprintf("%s %s", phase.name, roundTiming(phase.d)))
+		}
+	}
+	text := strings.Join(parts, ", ")
+	if s.ConnReused {
+		text += " (reused connection)"
+	}
+	return text
+}
+
+// roundTiming rounds d to the millisecond, keeping the sub-millisecond
+// phases of a local server from showing as 0s
+func roundTiming(d time.Duration) string {
+	if d < time.Millisecond {
+		return "<1ms"
+	}
+	return d.Round(time.Millisecond).String()
+}
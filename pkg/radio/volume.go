@@ -0,0 +1,101 @@
+package radio
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Volume curves map the 0-100% volume setting to an ffmpeg volume filter
+const (
+	CurveDB         = "db"         // -20..0 dB, linear in dB (the original mapping)
+	CurvePerceptual = "perceptual" // cubic amplitude, like PulseAudio's volume sliders
+	CurveLinear     = "linear"     // plain amplitude multiplier
+)
+
+var volumeCurves = []string{CurveDB, CurvePerceptual, CurveLinear}
+
+// ParseVolumeCurve validates a -volume-curve value
+func ParseVolumeCurve(s string) (string, error) {
+	curve := strings.ToLower(strings.TrimSpace(s))
+	for _, c := range volumeCurves {
+		if c == curve {
+			return curve, nil
+		}
+	}
+	return "", fmt.Errorf("unknown volume curve %q (use %s)", s, strings.Join(volumeCurves, ", "))
+}
+
+// minVolumeDB is the dB curve's level at 0%; it rises linearly to 0 dB
+// at 100%
+const minVolumeDB = -20.0
+
+// MaxBoostVolume is the highest volume WithVolumeBoost allows
+const MaxBoostVolume = 150
+
+// VolumeBoostDB returns the gain a volume past 100% adds, continuing the
+// dB curve's slope, so 150% is +10 dB; 0 up to 100%. It's the same on
+// every curve, since they all reach full volume at 100%.
+func VolumeBoostDB(percent int) float64 {
+	excess := math.Max(0, math.Min(MaxBoostVolume, float64(percent))-100)
+	return -minVolumeDB * excess / 100
+}
+
+// volumeFraction clamps a volume percentage and scales it to 0-1
+func volumeFraction(percent int) float64 {
+	return math.Max(0, math.Min(100, float64(percent))) / 100
+}
+
+// volumeDB returns the dB curve's gain for a volume percentage. It climbs
+// from minVolumeDB rather than scaling it, which would give -0 at 100%
+// and print as "-0.000000dB".
+func volumeDB(percent int) float64 {
+	return minVolumeDB - minVolumeDB*volumeFraction(percent)
+}
+
+// volumeGain returns the amplitude multiplier for a volume percentage
+func volumeGain(percent int, curve string) float64 {
+	x := volumeFraction(percent)
+	switch curve {
+	case CurvePerceptual:
+		return x * x * x
+	case CurveLinear:
+		return x
+	default:
+		return math.Pow(10, volumeDB(percent)/20)
+	}
+}
+
+// volumeFilter returns the ffmpeg volume filter for a volume percentage.
+// The dB curve keeps its original dB form; the others use a multiplier
+// so 0% is silent.
+func volumeFilter(percent int, curve string) string {
+	if percent > 100 {
+		return fmt.Sprintf("volume=%fdB", VolumeBoostDB(percent))
+	}
+	if curve == CurvePerceptual || curve == CurveLinear {
+		return fmt.Sprintf("volume=%.4f", volumeGain(percent, curve))
+	}
+	return fmt.Sprintf("volume=%fdB", volumeDB(percent))
+}
+
+// DefaultLoudnessTarget is the -normalize target, the common level for
+// streaming services
+const DefaultLoudnessTarget = -16.0
+
+// loudnormFilter returns an EBU R128 loudnorm filter aiming at target LUFS.
+// loudnorm works at 192 kHz internally, so resample back down after it.
+func loudnormFilter(target float64) string {
+	return fmt.Sprintf("loudnorm=I=%g:TP=-1.5:LRA=11,aresample=48000", target)
+}
+
+// SetNormalize turns on loudness normalization toward target LUFS
+func (p *Player) SetNormalize(target float64) error {
+	if target < -70 || target > -5 {
+		return fmt.Errorf("loudness target %g LUFS is out of range (use -70 to -5)", target)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.loudnorm = target
+	return nil
+}
@@ -0,0 +1,87 @@
+package radio
+
+import "testing"
+
+// afArg returns the -af argument of p's ffplay command line
+func afArg(t *testing.T, p *Player) string {
+	t.Helper()
+	args := p.ffplayArgs("station.mp3", streamFormat{})
+	for i, arg := range args[:len(args)-1] {
+		if arg == "-af" {
+			return args[i+1]
+		}
+	}
+	t.Fatalf("no -af in %q", args)
+	return ""
+}
+
+func TestVolumeFilter(t *testing.T) {
+	tests := []struct {
+		curve   string
+		percent int
+		want    string
+	}{
+		{CurveDB, 0, "volume=-20.000000dB"},
+		{CurveDB, 25, "volume=-15.000000dB"},
+		{CurveDB, 50, "volume=-10.000000dB"},
+		{CurveDB, 75, "volume=-5.000000dB"},
+		{CurveDB, 100, "volume=0.000000dB"},
+		{CurvePerceptual, 0, "volume=0.0000"},
+		{CurvePerceptual, 25, "volume=0.0156"},
+		{CurvePerceptual, 50, "volume=0.1250"},
+		{CurvePerceptual, 75, "volume=0.4219"},
+		{CurvePerceptual, 100, "volume=1.0000"},
+		{CurveLinear, 0, "volume=0.0000"},
+		{CurveLinear, 25, "volume=0.2500"},
+		{CurveLinear, 50, "volume=0.5000"},
+		{CurveLinear, 75, "volume=0.7500"},
+		{CurveLinear, 100, "volume=1.0000"},
+	}
+	for _, tt := range tests {
+		p := NewPlayer(WithVolumeCurve(tt.curve))
+		p.SetVolume(tt.percent)
+		if got := afArg(t, p); got != tt.want {
+			t.Errorf("%s curve at %d%%: -af %q, want %q", tt.curve, tt.percent, got, tt.want)
+		}
+	}
+}
+
+func TestVolumeClamping(t *testing.T) {
+	tests := []struct {
+		name    string
+		boost   bool
+		percent int
+		want    int
+	}{
+		{"negative", false, -10, 0},
+		{"past 100", false, 150, 100},
+		{"far past 100", false, 1000, 100},
+		{"negative with boost", true, -10, 0},
+		{"past the boost limit", true, 200, MaxBoostVolume},
+	}
+	for _, tt := range tests {
+		var opts []Option
+		if tt.boost {
+			opts = append(opts, WithVolumeBoost())
+		}
+		p := NewPlayer(opts...)
+		p.SetVolume(tt.percent)
+		if got := p.Volume(); got != tt.want {
+			t.Errorf("%s: SetVolume(%d) left the volume at %d, want %d", tt.name, tt.percent, got, tt.want)
+		}
+		// The filter must match a direct request for the clamped volume
+		q := NewPlayer(opts...)
+		q.SetVolume(tt.want)
+		if got, want := afArg(t, p), afArg(t, q); got != want {
+			t.Errorf("%s: SetVolume(%d) gives -af %q, want %q", tt.name, tt.percent, got, want)
+		}
+	}
+	// The pinned ends, without boost
+	for percent, want := range map[int]string{-10: "volume=-20.000000dB", 150: "volume=0.000000dB"} {
+		p := NewPlayer()
+		p.SetVolume(percent)
+		if got := afArg(t, p); got != want {
+			t.Errorf("SetVolume(%d): -af %q, want %q", percent, got, want)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package radio
+
+import (
+	"errors"
+	"strings"
+)
+
+// defaultYtdlpFormat is the format selector used for unknown qualities
+const defaultYtdlpFormat = "bestaudio/best"
+
+// qualityFormats map -quality values to yt-dlp format selectors
+var qualityFormats = map[string]string{
+	"low":    "worstaudio/worst",
+	"medium": "bestaudio[abr<=128]/worstaudio/worst",
+	"high":   defaultYtdlpFormat,
+}
+
+// QualityFormat returns the yt-dlp format selector for a quality name
+func QualityFormat(quality string) (string, bool) {
+	f, ok := qualityFormats[strings.ToLower(quality)]
+	if !ok {
+		return defaultYtdlpFormat, false
+	}
+	return f, true
+}
+
+// ytdlpFailure is a recognized reason yt-dlp couldn't resolve a URL
+type ytdlpFailure int
+
+const (
+	ytdlpUnknown ytdlpFailure = iota
+	ytdlpAgeRestricted
+	ytdlpPrivate
+	ytdlpUnavailable
+	ytdlpGeoBlocked
+	ytdlpRateLimited
+	ytdlpLoginRequired
+	ytdlpLiveEnded
+	ytdlpNetwork
+	ytdlpTimedOut
+)
+
+// ytdlpPatterns map lowercase stderr fragments to a failure. The first
+// match wins, so more specific messages come first.
+var ytdlpPatterns = []struct {
+	fragment string
+	kind     ytdlpFailure
+}{
+	{"confirm your age", ytdlpAgeRestricted},
+	{"age-restricted", ytdlpAgeRestricted},
+	{"inappropriate for some users", ytdlpAgeRestricted},
+	{"private video", ytdlpPrivate},
+	{"video is private", ytdlpPrivate},
+	{"not available in your country", ytdlpGeoBlocked},
+	{"not made this video available in your country", ytdlpGeoBlocked},
+	{"geo restrict", ytdlpGeoBlocked},
+	{"http error 429", ytdlpRateLimited},
+	{"too many requests", ytdlpRateLimited},
+	{"confirm you're not a bot", ytdlpRateLimited},
+	{"confirm you’re not a bot", ytdlpRateLimited},
+	{"members-only", ytdlpLoginRequired},
+	{"join this channel", ytdlpLoginRequired},
+	{"sign in", ytdlpLoginRequired},
+	{"this live event has ended", ytdlpLiveEnded},
+	{"premieres in", ytdlpLiveEnded},
+	{"video unavailable", ytdlpUnavailable},
+	{"has been removed", ytdlpUnavailable},
+	{"account associated with this video has been terminated", ytdlpUnavailable},
+	{"does not exist", ytdlpUnavailable},
+	{"http error 404", ytdlpUnavailable},
+	{"timed out", ytdlpNetwork},
+	{"connection reset", ytdlpNetwork},
+	{"connection refused", ytdlpNetwork},
+	{"remote end closed connection", ytdlpNetwork},
+	{"temporary failure in name resolution", ytdlpNetwork},
+	{"http error 500", ytdlpNetwork},
+	{"http error 502", ytdlpNetwork},
+	{"http error 503", ytdlpNetwork},
+	{"http error 504", ytdlpNetwork},
+}
+
+// ytdlpExplanations give a friendly reason and suggested fix per failure
+var ytdlpExplanations = map[ytdlpFailure][2]string{
+	ytdlpAgeRestricted: {"the video is age-restricted", "run with -cookies <file> or -cookies-from-browser <name> from a signed-in account, or pick a different station"},
+	ytdlpPrivate:       {"the video is private", "pick a different station"},
+	ytdlpUnavailable:   {"the video is unavailable or was removed", "the stream may have moved; update the station URL or pick a different station"},
+	ytdlpGeoBlocked:    {"the video isn't available in your country", "try a proxy in another region (-proxy) or pick a different station"},
+	ytdlpRateLimited:   {"YouTube is rate-limiting requests from this network", "wait a few minutes, or run with -cookies-from-browser <name>"},
+	ytdlpLoginRequired: {"the video requires signing in", "run with -cookies <file> or -cookies-from-browser <name> from an account with access"},
+	ytdlpLiveEnded:     {"the live stream isn't running right now", "try again later or pick a different station"},
+	ytdlpNetwork:       {"yt-dlp couldn't reach the site", "check your network connection and try again"},
+	ytdlpTimedOut:      {"yt-dlp took too long to respond", "check your network connection and try again"},
+}
+
+// transient reports whether a failure may go away on its own, so the
+// resolution is worth retrying
+func (f ytdlpFailure) transient() bool {
+	return f == ytdlpRateLimited || f == ytdlpNetwork || f == ytdlpTimedOut
+}
+
+// ResolveError is returned when yt-dlp can't turn a station URL into a
+// playable stream. Stderr keeps yt-dlp's own message for debugging.
+type ResolveError struct {
+	Kind   ytdlpFailure
+	Stderr string
+	Err    error
+}
+
+func (e *ResolveError) Error() string {
+	if exp, ok := ytdlpExplanations[e.Kind]; ok {
+		return exp[0]
+	}
+	if msg := ytdlpErrorLine(e.Stderr); msg != "" {
+		return "yt-dlp failed: " + msg
+	}
+	return "yt-dlp failed: " + e.Err.Error()
+}
+
+func (e *ResolveError) Unwrap() error { return e.Err }
+
+// Hint returns a suggested fix, or "" when the failure wasn't recognized
+func (e *ResolveError) Hint() string {
+	return ytdlpExplanations[e.Kind][1]
+}
+
+// ResolveHint returns the suggested fix for a start error caused by yt-dlp
+func ResolveHint(err error) string {
+	var resolveErr *ResolveError
+	if errors.As(err, &resolveErr) {
+		return resolveErr.Hint()
+	}
+	return ""
+}
+
+// classifyYtdlpError recognizes common failure messages in yt-dlp stderr
+func classifyYtdlpError(stderr string) ytdlpFailure {
+	lower := strings.ToLower(stderr)
+	for _, p := range ytdlpPatterns {
+		if strings.Contains(lower, p.fragment) {
+			return p.kind
+		}
+	}
+	return ytdlpUnknown
+}
+
+// ytdlpErrorLine picks the most useful line from yt-dlp's stderr: the
+// first "ERROR:" line without its prefix, else the last non-empty line
+func ytdlpErrorLine(stderr string) string {
+	var last string
+	for _, line := range strings.Split(stderr, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if msg, ok := strings.CutPrefix(line, "ERROR:"); ok {
+			return strings.TrimSpace(msg)
+		}
+		last = line
+	}
+	return last
+}